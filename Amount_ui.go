@@ -30,6 +30,7 @@ func (a AmountBarWidgeter) Create(f *Field) zui.View {
 			progress.SetColor(col)
 		}
 	}
+	setAmountColorsFromRules(progress, f)
 	return progress
 }
 
@@ -61,6 +62,7 @@ func (a AmountCircleWidgeter) Create(f *Field) zui.View {
 			view.ColorsFromValue[n] = zgeo.ColorFromString(f.Colors[i])
 		}
 	}
+	setAmountColorsFromRules(view, f) // colorif rules replace the default thresholds for the same values
 	return view
 }
 
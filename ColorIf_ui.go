@@ -0,0 +1,161 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/torlangballe/zui"
+	"github.com/torlangballe/zutil/zgeo"
+	"github.com/torlangballe/zutil/zlog"
+	"github.com/torlangballe/zutil/zreflect"
+	"github.com/torlangballe/zutil/ztime"
+)
+
+// ColorRule colors a field's view with Color when its value is more than Value, or less if Less is set.
+// For durations and times Value is in seconds. Times are compared by their age, or time left for until fields.
+type ColorRule struct {
+	Less    bool
+	OrEqual bool
+	Value   float64
+	Color   zgeo.Color
+}
+
+// parseColorRules parses a colorif tag's value, like ">80=orange|>95=red" or "60s=yellow|5m=red".
+// Values with units, like 90s, 5m or 1h30m, are durations parsed with time.ParseDuration, and stored as seconds. A rule without < or > is >=.
+func parseColorRules(str string) (rules []ColorRule, err error) {
+	for _, part := range strings.Split(str, "|") {
+		var r ColorRule
+		var sval, scol string
+		part = strings.TrimSpace(part)
+		i := strings.LastIndex(part, "=")
+		if i <= 0 {
+			return nil, errors.New("colorif rule has no =color: " + part)
+		}
+		sval, scol = part[:i], part[i+1:]
+		switch {
+		case strings.HasPrefix(sval, ">="):
+			r.OrEqual = true
+			sval = sval[2:]
+		case strings.HasPrefix(sval, "<="):
+			r.Less = true
+			r.OrEqual = true
+			sval = sval[2:]
+		case strings.HasPrefix(sval, ">"):
+			sval = sval[1:]
+		case strings.HasPrefix(sval, "<"):
+			r.Less = true
+			sval = sval[1:]
+		default:
+			r.OrEqual = true
+		}
+		sval = strings.TrimSpace(sval)
+		if sval != "" && unicode.IsLetter(rune(sval[len(sval)-1])) {
+			d, err := time.ParseDuration(sval)
+			if err != nil {
+				return nil, err
+			}
+			r.Value = ztime.DurSeconds(d)
+		} else {
+			n, err := strconv.ParseFloat(sval, 64)
+			if err != nil {
+				return nil, err
+			}
+			r.Value = n
+		}
+		r.Color = zgeo.ColorFromString(strings.TrimSpace(scol))
+		if !r.Color.Valid {
+			return nil, errors.New("colorif rule has bad color: " + part)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+func (r ColorRule) matches(n float64) bool {
+	if r.OrEqual && n == r.Value {
+		return true
+	}
+	if r.Less {
+		return n < r.Value
+	}
+	return n > r.Value
+}
+
+// getColorFromRules returns the color of the last of f's rules that n matches, so rules should go from mild to severe.
+func getColorFromRules(f *Field, n float64) (zgeo.Color, bool) {
+	var col zgeo.Color
+	got := false
+	for _, r := range f.ColorRules {
+		if r.matches(n) {
+			col = r.Color
+			got = true
+		}
+	}
+	return col, got
+}
+
+// getColorRuleValue returns the value of item that f's color rules are compared with.
+func getColorRuleValue(item zreflect.Item, f *Field) (float64, bool) {
+	if item.Kind == zreflect.KindTime {
+		t, _ := item.Interface.(time.Time)
+		if t.IsZero() {
+			return 0, false
+		}
+		since := ztime.Since(t)
+		if f.Flags&flagIsCountdown != 0 {
+			return -since, true
+		}
+		return since, true
+	}
+	if item.Package == "time" && item.TypeName == "Duration" {
+		return ztime.DurSeconds(time.Duration(item.Value.Int())), true
+	}
+	return getFloatFromTotalItem(item)
+}
+
+// setColorFromRules colors view with the rule of f that n matches, or as f's color tag or default if none do or got is false.
+func setColorFromRules(view zui.View, f *Field, n float64, got bool) {
+	if got {
+		col, matched := getColorFromRules(f, n)
+		if matched {
+			view.SetColor(col)
+			return
+		}
+	}
+	setColorFromField(view, f)
+}
+
+// setColorFromItemRules colors view from f's rules and item's value, if f has any.
+func setColorFromItemRules(view zui.View, f *Field, item zreflect.Item) {
+	if len(f.ColorRules) == 0 {
+		return
+	}
+	n, got := getColorRuleValue(item, f)
+	setColorFromRules(view, f, n, got)
+}
+
+// setAmountColorsFromRules sets an amount view's colors from the more-than rules of f, as they are colors from a value up.
+// A > rule's color starts just above its value. Less-than rules can't be shown this way, and are logged and ignored.
+func setAmountColorsFromRules(view *zui.AmountView, f *Field) {
+	for _, r := range f.ColorRules {
+		if r.Less {
+			zlog.Error(nil, "colorif less-than rule ignored for amount view:", f.FieldName, r.Value)
+			continue
+		}
+		if view.ColorsFromValue == nil {
+			view.ColorsFromValue = map[float64]zgeo.Color{}
+		}
+		n := r.Value
+		if !r.OrEqual {
+			n = math.Nextafter(n, math.Inf(1))
+		}
+		view.ColorsFromValue[n] = r.Color
+	}
+}
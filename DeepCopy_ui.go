@@ -0,0 +1,98 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+var (
+	mutexType   = reflect.TypeOf(sync.Mutex{})
+	rwMutexType = reflect.TypeOf(sync.RWMutex{})
+)
+
+// isLockType returns true for sync.Mutex and sync.RWMutex, which aren't copied, as they might be locked while copying.
+func isLockType(t reflect.Type) bool {
+	return t == mutexType || t == rwMutexType
+}
+
+// deepCopyValue returns a copy of val, with copies of what its pointers, slices, maps and interfaces refer to,
+// so the copy doesn't change when val does. Pointers to the same value are copied once, so cycles are kept.
+// Mutexes and pointers to them are new, unlocked ones in the copy, as the original is typically locked while copied.
+func deepCopyValue(val reflect.Value) reflect.Value {
+	if !val.IsValid() {
+		return val
+	}
+	return deepCopy(val, map[uintptr]reflect.Value{})
+}
+
+func deepCopy(val reflect.Value, copied map[uintptr]reflect.Value) reflect.Value {
+	if isLockType(val.Type()) {
+		return reflect.Zero(val.Type())
+	}
+	switch val.Kind() {
+	case reflect.Ptr:
+		if val.IsNil() {
+			return reflect.Zero(val.Type())
+		}
+		if isLockType(val.Type().Elem()) {
+			return reflect.New(val.Type().Elem())
+		}
+		n, got := copied[val.Pointer()]
+		if got {
+			return n
+		}
+		n = reflect.New(val.Type().Elem())
+		copied[val.Pointer()] = n
+		n.Elem().Set(deepCopy(val.Elem(), copied))
+		return n
+	case reflect.Slice:
+		if val.IsNil() {
+			return reflect.Zero(val.Type())
+		}
+		n := reflect.MakeSlice(val.Type(), val.Len(), val.Len())
+		for i := 0; i < val.Len(); i++ {
+			n.Index(i).Set(deepCopy(val.Index(i), copied))
+		}
+		return n
+	case reflect.Array:
+		n := reflect.New(val.Type()).Elem()
+		for i := 0; i < val.Len(); i++ {
+			n.Index(i).Set(deepCopy(val.Index(i), copied))
+		}
+		return n
+	case reflect.Map:
+		if val.IsNil() {
+			return reflect.Zero(val.Type())
+		}
+		n := reflect.MakeMapWithSize(val.Type(), val.Len())
+		iter := val.MapRange()
+		for iter.Next() {
+			n.SetMapIndex(iter.Key(), deepCopy(iter.Value(), copied))
+		}
+		return n
+	case reflect.Interface:
+		if val.IsNil() {
+			return reflect.Zero(val.Type())
+		}
+		n := reflect.New(val.Type()).Elem()
+		n.Set(deepCopy(val.Elem(), copied))
+		return n
+	case reflect.Struct:
+		n := reflect.New(val.Type()).Elem()
+		n.Set(val) // this copies unexported fields too, which are shared
+		for i := 0; i < n.NumField(); i++ {
+			field := n.Field(i)
+			if field.CanSet() {
+				field.Set(deepCopy(val.Field(i), copied))
+			} else if isLockType(field.Type()) { // unexported mutexes are cleared, as they were copied locked
+				reflect.NewAt(field.Type(), unsafe.Pointer(field.UnsafeAddr())).Elem().Set(reflect.Zero(field.Type()))
+			}
+		}
+		return n
+	}
+	return val
+}
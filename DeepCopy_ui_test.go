@@ -0,0 +1,69 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+type mutexRow struct {
+	sync.Mutex
+	Name string
+}
+
+type rwMutexRow struct {
+	sync.RWMutex
+	Name string
+}
+
+type rwMutexPointerRow struct {
+	*sync.RWMutex
+	Name string
+}
+
+type unexportedMutexRow struct {
+	lock sync.RWMutex
+	Name string
+}
+
+func (r *unexportedMutexRow) Lock()    { r.lock.Lock() }
+func (r *unexportedMutexRow) Unlock()  { r.lock.Unlock() }
+func (r *unexportedMutexRow) RLock()   { r.lock.RLock() }
+func (r *unexportedMutexRow) RUnlock() { r.lock.RUnlock() }
+
+// editCopy copies structPtr while it is locked, as PresentOKCancelStructCopy does, then builds, updates and edits the copy.
+func editCopy(t *testing.T, structPtr interface{}) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		unlock := lockStructure(structPtr, false)
+		edit := reflect.New(reflect.TypeOf(structPtr).Elem())
+		edit.Elem().Set(deepCopyValue(reflect.ValueOf(structPtr).Elem()))
+		unlock()
+		fv := FieldViewNew("copy", edit.Interface(), FieldViewParametersDefault())
+		fv.Build(true, true)
+		unlock = lockStructure(edit.Interface(), true)
+		edit.Elem().FieldByName("Name").SetString("edited")
+		unlock()
+		fv.Update(false)
+		unlock = lockStructure(structPtr, true) // the original must still be unlocked
+		unlock()
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("editing a copy of %T deadlocked", structPtr)
+	}
+}
+
+func TestEditCopyOfLockedStruct(t *testing.T) {
+	editCopy(t, &mutexRow{Name: "a"})
+	editCopy(t, &rwMutexRow{Name: "b"})
+	editCopy(t, &rwMutexPointerRow{RWMutex: &sync.RWMutex{}, Name: "c"})
+	editCopy(t, &unexportedMutexRow{Name: "d"})
+}
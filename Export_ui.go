@@ -0,0 +1,260 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/torlangballe/zui"
+	"github.com/torlangballe/zutil/zreflect"
+)
+
+type ExportFormat string
+
+const (
+	ExportCSV      ExportFormat = "csv"
+	ExportTSV      ExportFormat = "tsv"
+	ExportJSON     ExportFormat = "json"
+	ExportMarkdown ExportFormat = "md"
+)
+
+type ExportOptions struct {
+	RawValues bool                       // RawValues writes the values themselves, not the text shown in the ui
+	FieldIDs  []string                   // FieldIDs are the ids of columns to export, in that order. All columns if empty
+	SortOrder []zui.SortInfo             // SortOrder sorts a copy of the slice before export
+	Filter    func(row interface{}) bool // Filter is called with a pointer to each row, which is skipped if it returns false
+	NoHeader  bool                       // NoHeader skips the header line in CSV, TSV and Markdown
+}
+
+type exportColumn struct {
+	field *Field
+	title string
+}
+
+// ExportSlice writes the rows of slice (or pointer to slice) of structs in format, using field tags as a TableView would.
+// It doesn't create any views, so can be used for reports and tests.
+func ExportSlice(w io.Writer, slice interface{}, format ExportFormat, opts ExportOptions) error {
+	rval := reflect.ValueOf(slice)
+	if rval.Kind() == reflect.Ptr {
+		rval = rval.Elem()
+	}
+	if rval.Kind() != reflect.Slice {
+		return fmt.Errorf("ExportSlice: not a slice: %v", rval.Kind())
+	}
+	var structure interface{}
+	if rval.Len() == 0 {
+		structure = reflect.New(rval.Type().Elem()).Interface()
+	} else {
+		structure = rval.Index(0).Addr().Interface()
+	}
+	fields := makeTableFields(structure)
+	if len(opts.SortOrder) != 0 {
+		nval := reflect.MakeSlice(rval.Type(), rval.Len(), rval.Len())
+		copyRowsLocked(nval, rval)
+		SortSliceWithFields(nval.Interface(), fields, opts.SortOrder)
+		rval = nval
+	}
+	var rows []interface{}
+	for i := 0; i < rval.Len(); i++ {
+		row := rval.Index(i).Addr().Interface()
+		if opts.Filter == nil || opts.Filter(row) {
+			rows = append(rows, row)
+		}
+	}
+	return exportRows(w, fields, rows, format, opts)
+}
+
+// Export writes the rows of the table in its current sorting, skipping rows filtered out.
+// Columns are opts.FieldIDs, or the visible columns in their current order if it is empty.
+// opts.SortOrder sorts the rows differently, and opts.Filter skips rows, as in ExportSlice.
+func (v *TableView) Export(w io.Writer, format ExportFormat, opts ExportOptions) error {
+	if len(opts.FieldIDs) == 0 {
+		for _, f := range v.fields {
+			opts.FieldIDs = append(opts.FieldIDs, f.ID)
+		}
+	}
+	var rows []interface{}
+	count := v.GetRowCount()
+	for i := 0; i < count; i++ {
+		row := v.GetRowData(i)
+		if row != nil && !v.isDetailRow(i) && (opts.Filter == nil || opts.Filter(row)) {
+			rows = append(rows, row)
+		}
+	}
+	if len(opts.SortOrder) != 0 && len(rows) != 0 {
+		rval := reflect.MakeSlice(reflect.SliceOf(reflect.TypeOf(rows[0]).Elem()), len(rows), len(rows))
+		for i, row := range rows {
+			unlock := lockStructure(row, false)
+			rval.Index(i).Set(reflect.ValueOf(row).Elem())
+			unlock()
+		}
+		SortSliceWithFields(rval.Interface(), v.allFields, opts.SortOrder)
+		for i := range rows {
+			rows[i] = rval.Index(i).Addr().Interface()
+		}
+	}
+	return exportRows(w, v.allFields, rows, format, opts)
+}
+
+func makeExportColumns(fields []Field, ids []string) []exportColumn {
+	var columns []exportColumn
+	add := func(f *Field) {
+		if f.Kind == zreflect.KindFunc || f.Flags&(flagIsButton|flagIsTreeChildren) != 0 {
+			return
+		}
+		title := f.Title
+		if title == "" {
+			title = f.Name
+		}
+		columns = append(columns, exportColumn{field: f, title: title})
+	}
+	if len(ids) == 0 {
+		for i := range fields {
+			if fields[i].Flags&flagTableHide == 0 {
+				add(&fields[i])
+			}
+		}
+		return columns
+	}
+	for _, id := range ids {
+		for i := range fields {
+			if fields[i].ID == id {
+				add(&fields[i])
+				break
+			}
+		}
+	}
+	return columns
+}
+
+func getExportRawValue(item zreflect.Item) interface{} {
+	t, got := item.Interface.(time.Time)
+	if got {
+		if t.IsZero() {
+			return ""
+		}
+		return t.Format(time.RFC3339Nano)
+	}
+	return item.Interface
+}
+
+func getExportValue(item zreflect.Item, f *Field, children []zreflect.Item, raw bool) interface{} {
+	if raw {
+		return getExportRawValue(item)
+	}
+	return getItemDisplayString(item, f, children)
+}
+
+func exportRows(w io.Writer, fields []Field, rows []interface{}, format ExportFormat, opts ExportOptions) error {
+	columns := makeExportColumns(fields, opts.FieldIDs)
+	var table [][]interface{}
+	for _, row := range rows {
+		root, err := zreflect.ItterateStruct(row, zreflect.Options{UnnestAnonymous: true})
+		if err != nil {
+			return err
+		}
+		var line []interface{}
+		unlock := lockStructure(row, false)
+		for _, c := range columns {
+			item := root.Children[c.field.Index]
+			line = append(line, getExportValue(item, c.field, root.Children, opts.RawValues))
+		}
+		unlock()
+		table = append(table, line)
+	}
+	switch format {
+	case ExportCSV, ExportTSV:
+		return exportSeparated(w, columns, table, format, opts)
+	case ExportJSON:
+		return exportJSON(w, columns, table)
+	case ExportMarkdown:
+		return exportMarkdown(w, columns, table, opts)
+	}
+	return fmt.Errorf("unknown export format: %s", format)
+}
+
+func exportSeparated(w io.Writer, columns []exportColumn, table [][]interface{}, format ExportFormat, opts ExportOptions) error {
+	var lines [][]string
+	if !opts.NoHeader {
+		var header []string
+		for _, c := range columns {
+			header = append(header, c.title)
+		}
+		lines = append(lines, header)
+	}
+	for _, row := range table {
+		var line []string
+		for _, val := range row {
+			line = append(line, fmt.Sprint(val))
+		}
+		lines = append(lines, line)
+	}
+	if format == ExportTSV { // TSV has no quoting, so we replace tabs and newlines instead
+		replacer := strings.NewReplacer("\t", " ", "\r", " ", "\n", " ")
+		for _, line := range lines {
+			for i, str := range line {
+				line[i] = replacer.Replace(str)
+			}
+			_, err := io.WriteString(w, strings.Join(line, "\t")+"\n")
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	cw := csv.NewWriter(w)
+	err := cw.WriteAll(lines)
+	return err
+}
+
+func exportJSON(w io.Writer, columns []exportColumn, table [][]interface{}) error {
+	var parts []string
+	for _, row := range table { // we write objects ourselves, so keys are in column order
+		var fields []string
+		for i, val := range row {
+			key, _ := json.Marshal(columns[i].field.ID)
+			data, err := json.Marshal(val)
+			if err != nil {
+				return err
+			}
+			fields = append(fields, string(key)+": "+string(data))
+		}
+		parts = append(parts, "  {"+strings.Join(fields, ", ")+"}")
+	}
+	str := "[\n" + strings.Join(parts, ",\n") + "\n]\n"
+	if len(parts) == 0 {
+		str = "[]\n"
+	}
+	_, err := io.WriteString(w, str)
+	return err
+}
+
+func exportMarkdown(w io.Writer, columns []exportColumn, table [][]interface{}, opts ExportOptions) error {
+	escape := strings.NewReplacer("|", "\\|", "\r", "", "\n", "<br>")
+	var lines []string
+	if !opts.NoHeader {
+		var titles, dashes []string
+		for _, c := range columns {
+			titles = append(titles, escape.Replace(c.title))
+			dashes = append(dashes, "---")
+		}
+		lines = append(lines, "| "+strings.Join(titles, " | ")+" |")
+		lines = append(lines, "|"+strings.Join(dashes, "|")+"|")
+	}
+	for _, row := range table {
+		var cells []string
+		for _, val := range row {
+			cells = append(cells, escape.Replace(fmt.Sprint(val)))
+		}
+		lines = append(lines, "| "+strings.Join(cells, " | ")+" |")
+	}
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
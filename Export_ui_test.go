@@ -0,0 +1,121 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/torlangballe/zui"
+	"github.com/torlangballe/zutil/ztime"
+	"github.com/torlangballe/zutil/zwords"
+)
+
+type exportRow struct {
+	ID    string `zui:"id"`
+	Name  string `zui:"static"`
+	Count int    `zui:"static"`
+}
+
+var exportTestRows = []exportRow{
+	{ID: "1", Name: "a,b", Count: 3},
+	{ID: "2", Name: "x|y", Count: 1},
+}
+
+func testExport(t *testing.T, format ExportFormat, opts ExportOptions, want string) {
+	t.Helper()
+	var str strings.Builder
+	opts.RawValues = true
+	if len(opts.FieldIDs) == 0 {
+		opts.FieldIDs = []string{"name", "count"}
+	}
+	err := ExportSlice(&str, exportTestRows, format, opts)
+	if err != nil {
+		t.Fatal(format, err)
+	}
+	if str.String() != want {
+		t.Errorf("%s export:\n%q\nwant:\n%q", format, str.String(), want)
+	}
+}
+
+func TestExportCSV(t *testing.T) {
+	testExport(t, ExportCSV, ExportOptions{}, "Name,Count\n\"a,b\",3\nx|y,1\n")
+}
+
+func TestExportTSV(t *testing.T) {
+	testExport(t, ExportTSV, ExportOptions{}, "Name\tCount\na,b\t3\nx|y\t1\n")
+}
+
+func TestExportJSON(t *testing.T) {
+	testExport(t, ExportJSON, ExportOptions{}, "[\n  {\"name\": \"a,b\", \"count\": 3},\n  {\"name\": \"x|y\", \"count\": 1}\n]\n")
+}
+
+func TestExportMarkdown(t *testing.T) {
+	testExport(t, ExportMarkdown, ExportOptions{}, "| Name | Count |\n|---|---|\n| a,b | 3 |\n| x\\|y | 1 |\n")
+}
+
+func TestExportOptions(t *testing.T) {
+	opts := ExportOptions{
+		FieldIDs:  []string{"count", "name"},
+		SortOrder: []zui.SortInfo{{ID: "count", SmallFirst: true}},
+		NoHeader:  true,
+	}
+	testExport(t, ExportTSV, opts, "1\tx|y\n3\ta,b\n")
+
+	opts = ExportOptions{Filter: func(row interface{}) bool {
+		return row.(*exportRow).Count > 2
+	}}
+	testExport(t, ExportCSV, opts, "Name,Count\n\"a,b\",3\n")
+}
+
+type exportDisplayRow struct {
+	ID      string        `zui:"id"`
+	Level   int           `zui:"static,enum:exportlevel"`
+	Wait    time.Duration `zui:"static"`
+	Size    int           `zui:"static,format:memory"`
+	Created time.Time     `zui:"static,format:2006-01-02"`
+}
+
+// TestExportDisplayValues exports the texts a table shows, with enum names, durations, units and formatted times.
+func TestExportDisplayValues(t *testing.T) {
+	SetEnumItems("exportlevel", "Low", 1, "High", 2)
+	rows := []exportDisplayRow{
+		{ID: "1", Level: 2, Wait: 90 * time.Second, Size: 2048, Created: time.Date(2024, 3, 5, 10, 0, 0, 0, time.UTC)},
+	}
+	fields := makeTableFields(&rows[0])
+	var wait *Field
+	for i := range fields {
+		if fields[i].ID == "wait" {
+			wait = &fields[i]
+		}
+	}
+	if wait == nil {
+		t.Fatal("no wait field")
+	}
+	dur := ztime.GetSecsAsHMSString(90, wait.Flags&flagHasSeconds != 0, 0)
+	size := zwords.GetMemoryString(2048, "", 1)
+
+	var str strings.Builder
+	opts := ExportOptions{FieldIDs: []string{"level", "wait", "size", "created"}}
+	err := ExportSlice(&str, rows, ExportTSV, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Level\tWait\tSize\tCreated\nHigh\t" + dur + "\t" + size + "\t2024-03-05\n"
+	if str.String() != want {
+		t.Errorf("display export:\n%q\nwant:\n%q", str.String(), want)
+	}
+
+	str.Reset()
+	opts.RawValues = true
+	err = ExportSlice(&str, rows, ExportTSV, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = "Level\tWait\tSize\tCreated\n2\t1m30s\t2048\t2024-03-05T10:00:00Z\n"
+	if str.String() != want {
+		t.Errorf("raw export:\n%q\nwant:\n%q", str.String(), want)
+	}
+}
@@ -0,0 +1,120 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"reflect"
+
+	"github.com/torlangballe/zui"
+	"github.com/torlangballe/zutil/zgeo"
+	"github.com/torlangballe/zutil/zreflect"
+)
+
+var FieldViewDirtyMarkColor = zgeo.ColorNew(1, 0.6, 0.1, 1)
+
+// takeSnapshot stores a copy of the structure, which DirtyFields compares with.
+func (v *FieldView) takeSnapshot() {
+	defer v.lockStructure(false)()
+	v.snapshot = deepCopyValue(reflect.ValueOf(v.structure).Elem())
+	v.updateDirtyMarks()
+}
+
+// DirtyFields returns the ids of editable fields whose value has changed since the FieldView was built, or SetStructure or Commit called.
+// Sub-structs and slices are compared as a whole, and are dirty if anything in them changed.
+func (v *FieldView) DirtyFields() []string {
+	if !v.snapshot.IsValid() {
+		return nil
+	}
+	var ids []string
+	current := reflect.ValueOf(v.structure).Elem()
+	for _, f := range v.fields {
+		if f.IsStatic() { // static fields are changed by code, not edited
+			continue
+		}
+		now := current.FieldByName(f.FieldName)
+		was := v.snapshot.FieldByName(f.FieldName)
+		if now.IsValid() && was.IsValid() && !reflect.DeepEqual(now.Interface(), was.Interface()) {
+			ids = append(ids, f.ID)
+		}
+	}
+	return ids
+}
+
+func (v *FieldView) IsDirty() bool {
+	return len(v.DirtyFields()) != 0
+}
+
+// Commit makes the current values the ones DirtyFields compares with, typically after they are saved.
+func (v *FieldView) Commit() {
+	v.takeSnapshot()
+}
+
+// Revert sets dirty fields back to their values when built, or SetStructure or Commit was last called, sending EditedAction for each.
+func (v *FieldView) Revert() {
+	if !v.snapshot.IsValid() {
+		return
+	}
+	current := reflect.ValueOf(v.structure).Elem()
+	for _, id := range v.DirtyFields() {
+		f := v.findFieldWithID(id)
+		rval := current.FieldByName(f.FieldName)
+		rval.Set(deepCopyValue(v.snapshot.FieldByName(f.FieldName)))
+		if f.Kind == zreflect.KindSlice {
+			v.rebuildSliceField(f)
+		}
+		view, _ := v.findNamedViewOrInLabelized(f.ID)
+		v.callActionHandlerFunc(f, EditedAction, rval.Interface(), &view)
+	}
+	v.lastValues = nil // the reverted values might be the same as last shown, before they were edited
+	v.Update(false)
+	v.updateDirtyMarks()
+}
+
+// rebuildSliceField re-creates the views of slice field f, as it was built in buildItem.
+func (v *FieldView) rebuildSliceField(f *Field) {
+	view, _ := v.FindViewWithName(f.ID, true)
+	stack, _ := view.(*zui.StackView)
+	if stack == nil {
+		return
+	}
+	labelizeWidth := v.LabelizeWidth
+	if v.parentField != nil && v.LabelizeWidth == 0 {
+		labelizeWidth = v.parentField.LabelizeWidth
+	}
+	vert := v.Vertical && labelizeWidth == 0
+	sendEdited := false
+	v.updateSliceValue(v.structure, stack, vert, !v.HideStatic, f, sendEdited)
+}
+
+// updateDirtyMarks outlines the views of dirty fields if MarkDirty is set.
+func (v *FieldView) updateDirtyMarks() {
+	if !v.MarkDirty {
+		return
+	}
+	dirty := map[string]bool{}
+	for _, id := range v.DirtyFields() {
+		dirty[id] = true
+	}
+	for _, f := range v.fields {
+		view, _ := v.findNamedViewOrInLabelized(f.ID)
+		if view == nil {
+			continue
+		}
+		if dirty[f.ID] {
+			zui.ViewGetNative(view).SetStroke(1, FieldViewDirtyMarkColor)
+		} else {
+			zui.ViewGetNative(view).SetStroke(0, zgeo.Color{})
+		}
+	}
+}
+
+// updateDirtyMarksOfRoot updates the dirty marks of the nearest FieldView from v up that has a snapshot, after an edit.
+func (v *FieldView) updateDirtyMarksOfRoot() {
+	for fv := v; fv != nil; fv = fv.parent {
+		if fv.snapshot.IsValid() {
+			fv.updateDirtyMarks()
+			return
+		}
+	}
+}
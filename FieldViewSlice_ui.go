@@ -45,6 +45,35 @@ func makeCircledTrashButton() *zui.ShapeView {
 	return trash
 }
 
+// moveSliceElement moves element i of the slice field f in structure to before the element now at j.
+func moveSliceElement(structure interface{}, f *Field, i, j int) {
+	val, _ := zreflect.FindFieldWithNameInStruct(f.FieldName, structure, true)
+	moveInSlice(val, i, j)
+}
+
+func (v *FieldView) moveSliceElementWithUndo(structure interface{}, f *Field, i, j int, vertical, showStatic bool) {
+	val, _ := zreflect.FindFieldWithNameInStruct(f.FieldName, structure, true)
+	old := deepCopyValue(val)
+	moveSliceElement(structure, f, i, j)
+	v.recordSliceUndo(structure, f, old, vertical, showStatic)
+}
+
+// moveInSlice moves element i of slice val to index j, shifting the elements between.
+func moveInSlice(val reflect.Value, i, j int) {
+	if i < 0 || j < 0 || i >= val.Len() || j >= val.Len() || i == j {
+		return
+	}
+	swap := reflect.Swapper(val.Interface())
+	for i < j {
+		swap(i, i+1)
+		i++
+	}
+	for i > j {
+		swap(i, i-1)
+		i--
+	}
+}
+
 func (v *FieldView) updateSliceValue(structure interface{}, stack *zui.StackView, vertical, showStatic bool, f *Field, sendEdited bool) zui.View {
 	ct := stack.Parent().View.(zui.ContainerType)
 	newStack := v.buildStackFromSlice(structure, vertical, showStatic, f)
@@ -151,10 +180,28 @@ func (v *FieldView) buildStackFromSlice(structure interface{}, vertical, showSta
 				index := n
 				trash.SetPressedHandler(func() {
 					val, _ := zreflect.FindFieldWithNameInStruct(f.FieldName, structure, true)
+					old := deepCopyValue(val)
 					zslice.RemoveAt(val.Addr().Interface(), index)
+					v.recordSliceUndo(structure, f, old, vertical, showStatic)
 					// zlog.Info("newlen:", index, val.Len())
 					v.updateSliceValue(structure, stack, vertical, showStatic, f, true)
 				})
+				if f.Flags&flagIsOrdered != 0 {
+					for _, delta := range []int{-1, 1} {
+						text := "▲"
+						if delta == 1 {
+							text = "▼"
+						}
+						move := makeCircledTextButton(text, f)
+						fieldView.Add(move, zgeo.CenterLeft)
+						to := index + delta
+						move.SetPressedHandler(func() {
+							v.moveSliceElementWithUndo(structure, f, index, to, vertical, showStatic)
+							v.updateSliceValue(structure, stack, vertical, showStatic, f, true)
+						})
+						move.SetUsable(to >= 0 && to < sliceVal.Len())
+					}
+				}
 			}
 			stack.Add(fieldView, zgeo.TopLeft|zgeo.HorExpand)
 		}
@@ -175,6 +222,7 @@ func (v *FieldView) buildStackFromSlice(structure interface{}, vertical, showSta
 		plus := makeCircledImageButton("plus")
 		plus.SetPressedHandler(func() {
 			val, _ := zreflect.FindFieldWithNameInStruct(f.FieldName, structure, true)
+			old := deepCopyValue(val)
 			a := reflect.New(val.Type().Elem()).Elem()
 			nv := reflect.Append(val, a)
 			if fieldView != nil {
@@ -190,6 +238,7 @@ func (v *FieldView) buildStackFromSlice(structure interface{}, vertical, showSta
 			if fhItem != nil {
 				fhItem.HandleAction(f, NewStructAction, nil)
 			}
+			v.recordSliceUndo(structure, f, old, vertical, showStatic)
 			v.updateSliceValue(structure, stack, vertical, showStatic, f, true)
 			//			stack.CustomView.PressedHandler()()
 		})
@@ -206,7 +255,9 @@ func (v *FieldView) buildStackFromSlice(structure interface{}, vertical, showSta
 			zui.AlertAsk("Delete this entry?", func(ok bool) {
 				if ok {
 					val, _ := zreflect.FindFieldWithNameInStruct(f.FieldName, structure, true)
+					old := deepCopyValue(val)
 					zslice.RemoveAt(val.Addr().Interface(), selectedIndex)
+					v.recordSliceUndo(structure, f, old, vertical, showStatic)
 					// zlog.Info("newlen:", index, val.Len())
 					v.updateSliceValue(structure, stack, vertical, showStatic, f, true)
 				}
@@ -239,6 +290,24 @@ func (v *FieldView) buildStackFromSlice(structure interface{}, vertical, showSta
 			v.updateSliceValue(structure, stack, vertical, showStatic, f, false)
 		})
 		shape.SetUsable(selectedIndex < sliceVal.Len()-1)
+
+		if f.Flags&flagIsOrdered != 0 {
+			for _, delta := range []int{-1, 1} {
+				text := "↤"
+				if delta == 1 {
+					text = "↦"
+				}
+				shape = makeCircledTextButton(text, f)
+				bar.Add(shape, zgeo.CenterLeft)
+				to := selectedIndex + delta
+				shape.SetPressedHandler(func() {
+					v.moveSliceElementWithUndo(structure, f, selectedIndex, to, vertical, showStatic)
+					v.changeNamedSelectionIndex(to, f)
+					v.updateSliceValue(structure, stack, vertical, showStatic, f, true)
+				})
+				shape.SetUsable(to >= 0 && to < sliceVal.Len())
+			}
+		}
 	}
 	return stack
 }
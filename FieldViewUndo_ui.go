@@ -0,0 +1,172 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/torlangballe/zui"
+	"github.com/torlangballe/zutil/zreflect"
+)
+
+// undoCoalesceSecs is how long after an edit, another edit of the same field is merged into it, so typing is one entry.
+const undoCoalesceSecs = 1.5
+
+// undoEntry is a change of a field's value, made by editing it, or adding, removing or moving slice elements.
+type undoEntry struct {
+	fieldView  *FieldView
+	structure  interface{} // structure is the struct the field is in, as fieldView might have been re-used for another
+	field      *Field
+	oldValue   reflect.Value
+	newValue   reflect.Value
+	time       time.Time
+	isSlice    bool // isSlice is set for adding and removing slice elements, which re-builds the slice's views
+	vertical   bool // vertical and showStatic are used to re-build a slice's views
+	showStatic bool
+}
+
+// undoStack is the edits of a tree of FieldViews, kept by the root one.
+type undoStack struct {
+	entries  []*undoEntry
+	redos    []*undoEntry
+	applying bool
+}
+
+func (v *FieldView) getUndoStack() *undoStack {
+	root := v
+	for root.parent != nil {
+		root = root.parent
+	}
+	if root.undo == nil {
+		root.undo = &undoStack{}
+	}
+	return root.undo
+}
+
+func (v *FieldView) pushUndo(e *undoEntry) {
+	stack := v.getUndoStack()
+	if stack.applying || reflect.DeepEqual(e.oldValue.Interface(), e.newValue.Interface()) {
+		return
+	}
+	e.time = time.Now()
+	stack.redos = nil
+	if len(stack.entries) != 0 {
+		last := stack.entries[len(stack.entries)-1]
+		if !e.isSlice && !last.isSlice && last.fieldView == e.fieldView && last.structure == e.structure && last.field.ID == e.field.ID &&
+			time.Since(last.time).Seconds() < undoCoalesceSecs {
+			last.newValue = e.newValue
+			last.time = e.time
+			return
+		}
+	}
+	stack.entries = append(stack.entries, e)
+}
+
+// recordFieldUndo adds an undo entry for field f if item's value is different from old, a copy of its value before it was set.
+func (v *FieldView) recordFieldUndo(f *Field, item zreflect.Item, old reflect.Value) {
+	v.pushUndo(&undoEntry{fieldView: v, structure: v.structure, field: f, oldValue: old, newValue: deepCopyValue(item.Value)})
+}
+
+// recordSliceUndo adds an undo entry for slice field f in structure, with old being a copy of it before elements were added, removed or moved.
+func (v *FieldView) recordSliceUndo(structure interface{}, f *Field, old reflect.Value, vertical, showStatic bool) {
+	val, _ := zreflect.FindFieldWithNameInStruct(f.FieldName, structure, true)
+	e := &undoEntry{fieldView: v, structure: structure, field: f, oldValue: old, newValue: deepCopyValue(val), isSlice: true}
+	e.vertical = vertical
+	e.showStatic = showStatic
+	v.pushUndo(e)
+}
+
+func (e *undoEntry) apply(val reflect.Value) {
+	fv := e.fieldView
+	rval, found := zreflect.FindFieldWithNameInStruct(e.field.FieldName, e.structure, true)
+	if !found {
+		return
+	}
+	rval.Set(deepCopyValue(val))
+	if fv.structure != e.structure { // the view shows something else now
+		return
+	}
+	fv.lastValues = nil // the value set might be the same as last shown, before it was edited
+	if e.isSlice {
+		view, _ := fv.FindViewWithName(e.field.ID, true)
+		stack, _ := view.(*zui.StackView)
+		if stack != nil {
+			sendEdited := true
+			fv.updateSliceValue(e.structure, stack, e.vertical, e.showStatic, e.field, sendEdited)
+		}
+		return
+	}
+	fv.Update(false)
+	view, _ := fv.findNamedViewOrInLabelized(e.field.ID)
+	fv.callActionHandlerFunc(e.field, EditedAction, rval.Interface(), &view)
+}
+
+func (v *FieldView) CanUndo() bool {
+	return len(v.getUndoStack().entries) != 0
+}
+
+func (v *FieldView) CanRedo() bool {
+	return len(v.getUndoStack().redos) != 0
+}
+
+// Undo sets the field of the last edit in v's tree of FieldViews back to its value before, sending EditedAction.
+// It returns false if there was nothing to undo.
+func (v *FieldView) Undo() bool {
+	stack := v.getUndoStack()
+	if len(stack.entries) == 0 {
+		return false
+	}
+	e := stack.entries[len(stack.entries)-1]
+	stack.entries = stack.entries[:len(stack.entries)-1]
+	stack.redos = append(stack.redos, e)
+	stack.applying = true
+	e.apply(e.oldValue)
+	stack.applying = false
+	return true
+}
+
+// Redo makes the last undone edit again.
+func (v *FieldView) Redo() bool {
+	stack := v.getUndoStack()
+	if len(stack.redos) == 0 {
+		return false
+	}
+	e := stack.redos[len(stack.redos)-1]
+	stack.redos = stack.redos[:len(stack.redos)-1]
+	stack.entries = append(stack.entries, e)
+	stack.applying = true
+	e.apply(e.newValue)
+	stack.applying = false
+	return true
+}
+
+// ClearUndo removes all undo and redo entries, typically after the edits have been saved.
+func (v *FieldView) ClearUndo() {
+	stack := v.getUndoStack()
+	stack.entries = nil
+	stack.redos = nil
+}
+
+// handleUndoKey undoes with ctrl/cmd-Z, and redoes with shift-ctrl/cmd-Z or ctrl-Y.
+func (v *FieldView) handleUndoKey(key zui.KeyboardKey, mods zui.KeyboardModifier) bool {
+	if mods&(zui.KeyboardModifierControl|zui.KeyboardModifierCommand) == 0 {
+		return false
+	}
+	switch key {
+	case 'z', 'Z':
+		if mods&zui.KeyboardModifierShift != 0 {
+			v.Redo()
+		} else {
+			v.Undo()
+		}
+		return true
+	case 'y', 'Y':
+		if mods&zui.KeyboardModifierControl != 0 {
+			v.Redo()
+			return true
+		}
+	}
+	return false
+}
@@ -5,9 +5,11 @@ package zfields
 
 import (
 	"fmt"
+	"math"
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/torlangballe/zui"
@@ -30,11 +32,20 @@ type FieldView struct {
 	parent      *FieldView
 	fields      []Field
 	parentField *Field
-	structure   interface{} // structure of ALL, not just a row
-	changed     bool
+	structure   interface{}   // structure of ALL, not just a row
+	snapshot    reflect.Value // snapshot is a copy of structure when built, to find dirty fields
+	editLock    sync.Mutex
+	editedTimes map[string]time.Time // editedTimes are when the view's fields were last edited, by field id
+	lockDepth   int                  // lockDepth is how many times the structure is locked by lockStructure, only in the outermost FieldView
+	timeLabels  map[*zui.Label]bool  // timeLabels are labels registered with the shared time ticker
+	timesUp     map[string]time.Time // timesUp are the times of until fields TimeUpAction was sent for, by field id
 	//	oldStructure  interface{}
-	id           string
-	handleUpdate func(edited bool)
+	id             string
+	handleUpdate   func(edited bool)
+	editedHandlers []func(f *Field)       // editedHandlers are called after any field of the view is edited, see addEditedHandler
+	lastValues     map[string]interface{} // lastValues are copies of field values when their views were last updated, by field id
+	undo           *undoStack             // undo is the edits of this and its child FieldViews, set in the root one
+	fieldsOrdered  bool                   // fieldsOrdered builds fields in the order of fields, not the struct, for table rows with a state applied
 	FieldViewParameters
 	//	getSubStruct  func(structID string, direct bool) interface{}
 }
@@ -44,33 +55,81 @@ type FieldViewParameters struct {
 	Spacing       float64
 	LabelizeWidth float64
 	ImmediateEdit bool
+	MarkDirty     bool // MarkDirty outlines fields that have been changed since built or committed
+	// EditHoldOffSecs is how long after an edit Update(dontOverwriteEdited=true) leaves the edited field alone.
+	// Zero is the default of 10 seconds, and a negative value makes Update always overwrite edits.
+	EditHoldOffSecs float64
 }
 
 func FieldViewParametersDefault() FieldViewParameters {
-	return FieldViewParameters{ImmediateEdit: true, Spacing: 10}
+	return FieldViewParameters{ImmediateEdit: true, Spacing: 10, EditHoldOffSecs: fieldViewDefaultEditHoldOffSecs}
 }
 
-var fieldViewEdited = map[string]time.Time{}
-
 func (v *FieldView) Struct() interface{} {
 	return v.structure
 }
 
-func setFieldViewEdited(fv *FieldView) {
-	// zlog.Info("FV Edited:", fv.Hierarchy())
-	fieldViewEdited[fv.Hierarchy()] = time.Now()
+const fieldViewDefaultEditHoldOffSecs = 10
+
+// getEditHoldOffSecs returns EditHoldOffSecs, or the default if it is zero, as in parameters not made with FieldViewParametersDefault.
+func (v *FieldView) getEditHoldOffSecs() float64 {
+	if v.EditHoldOffSecs == 0 {
+		return fieldViewDefaultEditHoldOffSecs
+	}
+	return v.EditHoldOffSecs
 }
 
-func IsFieldViewEditedRecently(fv *FieldView) bool {
-	h := fv.Hierarchy()
-	t, got := fieldViewEdited[h]
-	if got {
-		if time.Since(t) < time.Second*10 {
-			return true
+// setEdited marks field f of v as edited now.
+func (v *FieldView) setEdited(f *Field) {
+	v.editLock.Lock()
+	if v.editedTimes == nil {
+		v.editedTimes = map[string]time.Time{}
+	}
+	v.editedTimes[f.ID] = time.Now()
+	v.editLock.Unlock()
+}
+
+// EditedTime returns when a field in v was last edited, or zero time if not since created or ClearEdited.
+// Fields in FieldViews inside v, for sub-structs and slices, have their own edit times.
+func (v *FieldView) EditedTime() time.Time {
+	v.editLock.Lock()
+	defer v.editLock.Unlock()
+	var last time.Time
+	for _, t := range v.editedTimes {
+		if t.After(last) {
+			last = t
 		}
-		delete(fieldViewEdited, h)
 	}
-	return false
+	return last
+}
+
+// ClearEdited forgets v's fields were edited, so Update overwrites them again.
+func (v *FieldView) ClearEdited() {
+	v.editLock.Lock()
+	v.editedTimes = nil
+	v.editLock.Unlock()
+}
+
+// IsEditedRecently returns true if a field in v was edited less than EditHoldOffSecs ago.
+func (v *FieldView) IsEditedRecently() bool {
+	return v.isEditedRecently(v.EditedTime())
+}
+
+// IsFieldEditedRecently returns true if the field with id fieldID was edited less than EditHoldOffSecs ago.
+func (v *FieldView) IsFieldEditedRecently(fieldID string) bool {
+	v.editLock.Lock()
+	t := v.editedTimes[fieldID]
+	v.editLock.Unlock()
+	return v.isEditedRecently(t)
+}
+
+func (v *FieldView) isEditedRecently(t time.Time) bool {
+	holdOff := v.getEditHoldOffSecs()
+	return holdOff > 0 && !t.IsZero() && ztime.Since(t) < holdOff
+}
+
+func IsFieldViewEditedRecently(fv *FieldView) bool {
+	return fv.IsEditedRecently()
 }
 
 func fieldViewNew(id string, vertical bool, structure interface{}, params FieldViewParameters, marg zgeo.Size, parent *FieldView) *FieldView {
@@ -84,6 +143,10 @@ func fieldViewNew(id string, vertical bool, structure interface{}, params FieldV
 	v.FieldViewParameters = params
 	v.id = id
 	v.parent = parent
+	v.AddOnRemoveFunc(func() {
+		v.ClearEdited()
+		unregisterChangeListener(v)
+	})
 	children := v.getStructItems()
 
 	for i, item := range children {
@@ -104,10 +167,15 @@ func (v *FieldView) Build(update, showStatic bool) {
 		a |= zgeo.VertCenter
 	}
 	v.buildStack(v.ObjectName(), a, showStatic, zgeo.Size{}, true, 5) // Size{6, 4}
+	if v.parent == nil {
+		v.SetKeyHandler(v.handleUndoKey)
+		v.listenForChanges()
+	}
 	if update {
 		dontOverwriteEdited := false
 		v.Update(dontOverwriteEdited)
 	}
+	v.takeSnapshot()
 }
 
 func (v *FieldView) findNamedViewOrInLabelized(name string) (view, maybeLabel zui.View) {
@@ -236,11 +304,6 @@ func (v *FieldView) updateShowEnableOnView(view zui.View, isShow bool, toID stri
 }
 
 func (v *FieldView) Update(dontOverwriteEdited bool) {
-	// zlog.Info("fv.Update:", v.ObjectName(), dontOverwriteEdited, IsFieldViewEditedRecently(v))
-	if dontOverwriteEdited && IsFieldViewEditedRecently(v) {
-		// zlog.Info("FV No Update, edited", v.Hierarchy())
-		return
-	}
 	children := v.getStructItems()
 	fh, _ := v.structure.(ActionHandler)
 	sview := v.View
@@ -249,184 +312,241 @@ func (v *FieldView) Update(dontOverwriteEdited bool) {
 	}
 	// fmt.Println("FV Update", v.id, len(children))
 	// fmt.Printf("FV Update: %s %d %+v\n", v.id, len(children), v.structure)
+	changed := false
+	unlock := v.lockStructure(false)
 	for i, item := range children {
 		f := findFieldWithIndex(&v.fields, i)
 		if f == nil {
 			// zlog.Info("FV Update no index found:", i, v.id)
 			continue
 		}
-		// fmt.Println("FV Update Item:", f.Name)
-		fview, flabelized := v.findNamedViewOrInLabelized(f.ID)
-		// zlog.Info("fv.UpdateF:", v.ObjectName(), f.FieldName, fview != nil)
-		if fview == nil {
-			// zlog.Info("FV Update no view found:", i, v.id, f.ID)
+		if dontOverwriteEdited && v.IsFieldEditedRecently(f.ID) {
+			// zlog.Info("FV No Update, edited", v.Hierarchy(), f.ID)
 			continue
 		}
-		v.updateShowEnableOnView(flabelized, true, fview.ObjectName())
-		v.updateShowEnableOnView(flabelized, false, fview.ObjectName())
-		called := v.callActionHandlerFunc(f, DataChangedAction, item.Address, &fview)
-		// zlog.Info("fv.Update:", v.ObjectName(), f.ID, called)
-		if called {
-			// fmt.Println("FV Update called", v.id, f.Kind, f.ID)
-			continue
+		if v.updateField(f, item, children, dontOverwriteEdited) {
+			changed = true
 		}
-		if f.Kind != zreflect.KindSlice {
-			w := widgeters[f.WidgetName]
-			if w != nil {
-				// zlog.Info("WidgeterSetVal:", zui.ViewGetNative(fview).Hierarchy())
-				w.SetValue(fview, item.Interface)
-				continue
+	}
+	unlock()
+	// call general one with no id. Needs to be after above loop, so values set
+	if fh != nil && changed {
+		fh.HandleAction(nil, DataChangedAction, &sview)
+	}
+}
+
+// isFieldValueUnchanged returns true if item has the same value as when f's view was last updated, storing a copy of it if not.
+func (v *FieldView) isFieldValueUnchanged(f *Field, item zreflect.Item) bool {
+	if v.lastValues == nil {
+		v.lastValues = map[string]interface{}{}
+	}
+	last, got := v.lastValues[f.ID]
+	if got && reflect.DeepEqual(last, item.Interface) {
+		return true
+	}
+	v.lastValues[f.ID] = deepCopyValue(item.Value).Interface()
+	return false
+}
+
+// storeLastValue stores a copy of item's value as the one last shown by f's view, after it is edited.
+func (v *FieldView) storeLastValue(f *Field, item zreflect.Item) {
+	if v.lastValues != nil {
+		v.lastValues[f.ID] = deepCopyValue(item.Value).Interface()
+	}
+}
+
+// forgetLastValue removes the value last shown by f's view, when Update doesn't set it as it's being edited.
+// Otherwise a value changed during the edit would never be shown if the edit is abandoned.
+func (v *FieldView) forgetLastValue(f *Field) {
+	delete(v.lastValues, f.ID)
+}
+
+// alwaysUpdateField returns true for fields that show time passing, or are colored by age or rules, depend on other fields, or are sub-structs, which compare their own fields.
+func alwaysUpdateField(f *Field) bool {
+	return f.Flags&flagIsDuration != 0 || f.OldSecs != 0 || len(f.ColorRules) != 0 || f.LocalEnum != "" || f.Kind == zreflect.KindStruct
+}
+
+// updateField updates the view of field f from item, one of the struct's items in children.
+// Fields with the same value as last time they were updated are skipped, unless they show time passing or depend on other fields.
+// It returns true if f's value had changed.
+func (v *FieldView) updateField(f *Field, item zreflect.Item, children []zreflect.Item, dontOverwriteEdited bool) bool {
+	fview, flabelized := v.findNamedViewOrInLabelized(f.ID)
+	// zlog.Info("fv.UpdateF:", v.ObjectName(), f.FieldName, fview != nil)
+	if fview == nil {
+		// zlog.Info("FV Update no view found:", i, v.id, f.ID)
+		return false
+	}
+	v.updateShowEnableOnView(flabelized, true, fview.ObjectName())
+	v.updateShowEnableOnView(flabelized, false, fview.ObjectName())
+	changed := !v.isFieldValueUnchanged(f, item)
+	if !changed && !alwaysUpdateField(f) {
+		return false
+	}
+	called := changed && v.callActionHandlerFunc(f, DataChangedAction, item.Address, &fview)
+	// zlog.Info("fv.Update:", v.ObjectName(), f.ID, called)
+	if called {
+		// fmt.Println("FV Update called", v.id, f.Kind, f.ID)
+		return changed
+	}
+	if f.Kind != zreflect.KindSlice {
+		w := widgeters[f.WidgetName]
+		if w != nil {
+			// zlog.Info("WidgeterSetVal:", zui.ViewGetNative(fview).Hierarchy())
+			w.SetValue(fview, item.Interface)
+			return changed
+		}
+	}
+	menuType, _ := fview.(zui.MenuType)
+	if menuType != nil && ((f.Enum != "" && f.Kind != zreflect.KindSlice) || f.LocalEnum != "") {
+		var enum zdict.Items
+		// zlog.Info("Update FV: Menu:", f.Name, f.Enum, f.LocalEnum)
+		if f.Enum != "" {
+			enum, _ = fieldEnums[f.Enum]
+			// zlog.Info("UpdateStack Enum:", f.Name)
+			// zdict.DumpNamedValues(enum)
+		} else {
+			ei := findLocalFieldWithID(&children, f.LocalEnum)
+			zlog.Assert(ei != nil, f.Name, f.LocalEnum)
+			enum = ei.Interface.(zdict.ItemsGetter).GetItems()
+		}
+		// zlog.Assert(enum != nil, f.Name, f.LocalEnum, f.Enum)
+		// zlog.Info("Update FV: Menu2:", f.Name, enum, item.Interface)
+		menuType.UpdateItems(enum, []interface{}{item.Interface})
+		return changed
+	}
+	if menuType == nil && f.Kind == zreflect.KindSlice {
+		// val, found := zreflect.FindFieldWithNameInStruct(f.FieldName, v.structure, true)
+		// fmt.Printf("updateSliceFieldView: %s %p %p %v %p\n", v.id, item.Interface, val.Interface(), found, fview)
+		var selectedIndex int
+		if f.Flags&flagIsNamedSelection != 0 {
+			selectedIndex, _ = zui.DefaultLocalKeyValueStore.GetInt(v.makeNamedSelectionKey(f), 0)
+		}
+		updateSliceFieldView(fview, selectedIndex, item, f, dontOverwriteEdited)
+	}
+	updateItemLocalToolTip(f, children, fview)
+	if f.IsStatic() {
+		zuistringer, _ := item.Interface.(UIStringer)
+		if zuistringer != nil {
+			label, _ := fview.(*zui.Label)
+			if label != nil {
+				label.SetText(zuistringer.ZUIString())
+				return changed
 			}
 		}
-		menuType, _ := fview.(zui.MenuType)
-		if menuType != nil && ((f.Enum != "" && f.Kind != zreflect.KindSlice) || f.LocalEnum != "") {
-			var enum zdict.Items
-			// zlog.Info("Update FV: Menu:", f.Name, f.Enum, f.LocalEnum)
-			if f.Enum != "" {
-				enum, _ = fieldEnums[f.Enum]
-				// zlog.Info("UpdateStack Enum:", f.Name)
-				// zdict.DumpNamedValues(enum)
-			} else {
-				ei := findLocalFieldWithID(&children, f.LocalEnum)
-				zlog.Assert(ei != nil, f.Name, f.LocalEnum)
-				enum = ei.Interface.(zdict.ItemsGetter).GetItems()
+	}
+	switch f.Kind {
+	case zreflect.KindSlice:
+		getter, _ := item.Interface.(zdict.ItemsGetter)
+		if getter != nil {
+			items := getter.GetItems()
+			mt := fview.(zui.MenuType)
+			// zlog.Info("fv update slice:", f.Name, len(items), mt != nil, reflect.ValueOf(fview).Type())
+			if mt != nil {
+				// assert menu is static...
+				mt.UpdateItems(items, nil)
 			}
-			// zlog.Assert(enum != nil, f.Name, f.LocalEnum, f.Enum)
-			// zlog.Info("Update FV: Menu2:", f.Name, enum, item.Interface)
-			menuType.UpdateItems(enum, []interface{}{item.Interface})
-			continue
 		}
-		if menuType == nil && f.Kind == zreflect.KindSlice {
+	case zreflect.KindTime:
+		tv, _ := fview.(*zui.TextView)
+		if tv != nil && tv.IsEditing() {
+			v.forgetLastValue(f) // not shown, so it is set when the next Update finds it isn't being edited
+			break
+		}
+		if f.Flags&flagIsDuration != 0 {
 			// val, found := zreflect.FindFieldWithNameInStruct(f.FieldName, v.structure, true)
-			// fmt.Printf("updateSliceFieldView: %s %p %p %v %p\n", v.id, item.Interface, val.Interface(), found, fview)
-			var selectedIndex int
-			if f.Flags&flagIsNamedSelection != 0 {
-				selectedIndex, _ = zui.DefaultLocalKeyValueStore.GetInt(v.makeNamedSelectionKey(f), 0)
+			// if found {
+			// t := val.Interface().(time.Time)
+			// fmt.Println("FV Update Time Dur", v.id, time.Since(t))
+			// }
+			v.updateSinceTime(fview.(*zui.Label), f)
+			break
+		}
+		str := getTimeString(item, f)
+		to := fview.(zui.TextLayoutOwner)
+		to.SetText(str)
+		setColorFromItemRules(fview, f, item)
+
+	case zreflect.KindStruct:
+		fv, _ := fview.(*FieldView)
+		if fv == nil {
+			break
+		}
+		//			fv.SetStructure(item.Interface)
+		fv.Update(dontOverwriteEdited)
+		break
+
+	case zreflect.KindBool:
+		cv, _ := fview.(*zui.CheckBox) // it might be a button or something instead
+		if cv != nil {
+			b := zbool.ToBoolInd(item.Value.Interface().(bool))
+			v := cv.Value()
+			if v != b {
+				cv.SetValue(b)
 			}
-			updateSliceFieldView(fview, selectedIndex, item, f, dontOverwriteEdited)
 		}
-		updateItemLocalToolTip(f, children, fview)
+
+	case zreflect.KindInt, zreflect.KindFloat:
+		_, got := item.Interface.(zbool.BitsetItemsOwner)
+		if got {
+			updateFlagStack(item, f, fview)
+		}
+
+		str := getTextFromNumberishItem(item, f)
 		if f.IsStatic() {
-			zuistringer, _ := item.Interface.(UIStringer)
-			if zuistringer != nil {
-				label, _ := fview.(*zui.Label)
-				if label != nil {
-					label.SetText(zuistringer.ZUIString())
-					continue
-				}
+			label, _ := fview.(*zui.Label)
+			if label != nil {
+				label.SetText(str)
+				setColorFromItemRules(label, f, item)
 			}
+			break
 		}
-		switch f.Kind {
-		case zreflect.KindSlice:
-			getter, _ := item.Interface.(zdict.ItemsGetter)
-			if getter != nil {
-				items := getter.GetItems()
-				mt := fview.(zui.MenuType)
-				// zlog.Info("fv update slice:", f.Name, len(items), mt != nil, reflect.ValueOf(fview).Type())
-				if mt != nil {
-					// assert menu is static...
-					mt.UpdateItems(items, nil)
-				}
-			}
-		case zreflect.KindTime:
-			tv, _ := fview.(*zui.TextView)
-			if tv != nil && tv.IsEditing() {
-				break
-			}
-			if f.Flags&flagIsDuration != 0 {
-				// val, found := zreflect.FindFieldWithNameInStruct(f.FieldName, v.structure, true)
-				// if found {
-				// t := val.Interface().(time.Time)
-				// fmt.Println("FV Update Time Dur", v.id, time.Since(t))
-				// }
-				v.updateSinceTime(fview.(*zui.Label), f)
-				break
-			}
-			str := getTimeString(item, f)
-			to := fview.(zui.TextLayoutOwner)
-			to.SetText(str)
-
-		case zreflect.KindStruct:
-			fv, _ := fview.(*FieldView)
-			if fv == nil {
+		tv, _ := fview.(*zui.TextView)
+		if tv != nil {
+			if tv.IsEditing() {
+				v.forgetLastValue(f)
 				break
 			}
-			//			fv.SetStructure(item.Interface)
-			fv.Update(dontOverwriteEdited)
-			break
+			tv.SetText(str)
+			setColorFromItemRules(tv, f, item)
+		}
 
-		case zreflect.KindBool:
-			cv, _ := fview.(*zui.CheckBox) // it might be a button or something instead
-			if cv != nil {
-				b := zbool.ToBoolInd(item.Value.Interface().(bool))
-				v := cv.Value()
-				if v != b {
-					cv.SetValue(b)
-				}
+	case zreflect.KindString, zreflect.KindFunc:
+		str := item.Value.String()
+		if f.Flags&flagIsImage != 0 {
+			// zlog.Info("FVUpdate SETIMAGE:", f.Name, str)
+			path := ""
+			if f.Kind == zreflect.KindString {
+				path = str
 			}
-
-		case zreflect.KindInt, zreflect.KindFloat:
-			_, got := item.Interface.(zbool.BitsetItemsOwner)
-			if got {
-				updateFlagStack(item, f, fview)
+			if path != "" && strings.Contains(f.ImageFixedPath, "*") {
+				path = strings.Replace(f.ImageFixedPath, "*", path, 1)
+			} else if path == "" || f.Flags&flagIsFixed != 0 {
+				path = f.ImageFixedPath
 			}
-
-			str := getTextFromNumberishItem(item, f)
+			io := fview.(zimage.Owner)
+			io.SetImage(nil, path, nil)
+		} else {
 			if f.IsStatic() {
 				label, _ := fview.(*zui.Label)
 				if label != nil {
+					if f.Flags&flagIsFixed != 0 {
+						str = f.Name
+					}
 					label.SetText(str)
 				}
-				break
-			}
-			tv, _ := fview.(*zui.TextView)
-			if tv != nil {
-				if tv.IsEditing() {
-					break
-				}
-				tv.SetText(str)
-			}
-
-		case zreflect.KindString, zreflect.KindFunc:
-			str := item.Value.String()
-			if f.Flags&flagIsImage != 0 {
-				// zlog.Info("FVUpdate SETIMAGE:", f.Name, str)
-				path := ""
-				if f.Kind == zreflect.KindString {
-					path = str
-				}
-				if path != "" && strings.Contains(f.ImageFixedPath, "*") {
-					path = strings.Replace(f.ImageFixedPath, "*", path, 1)
-				} else if path == "" || f.Flags&flagIsFixed != 0 {
-					path = f.ImageFixedPath
-				}
-				io := fview.(zimage.Owner)
-				io.SetImage(nil, path, nil)
 			} else {
-				if f.IsStatic() {
-					label, _ := fview.(*zui.Label)
-					if label != nil {
-						if f.Flags&flagIsFixed != 0 {
-							str = f.Name
-						}
-						label.SetText(str)
-					}
-				} else {
-					tv, _ := fview.(*zui.TextView)
-					if tv != nil {
-						if tv.IsEditing() {
-							break
-						}
-						tv.SetText(str)
+				tv, _ := fview.(*zui.TextView)
+				if tv != nil {
+					if tv.IsEditing() {
+						v.forgetLastValue(f)
+						break
 					}
+					tv.SetText(str)
 				}
 			}
 		}
 	}
-	// call general one with no id. Needs to be after above loop, so values set
-	if fh != nil {
-		fh.HandleAction(nil, DataChangedAction, &sview)
-	}
+	return changed
 }
 
 func FieldViewNew(id string, structure interface{}, params FieldViewParameters) *FieldView {
@@ -437,6 +557,7 @@ func FieldViewNew(id string, structure interface{}, params FieldViewParameters)
 func (v *FieldView) SetStructure(s interface{}) {
 	// fmt.Printf("FV SetStruct: %s %p\n", v.ObjectName(), s)
 	v.structure = s
+	v.lastValues = nil // values shown are from the old structure
 	for _, c := range v.getStructItems() {
 		if c.Kind == zreflect.KindStruct {
 			id := fieldNameToID(c.FieldName)
@@ -447,8 +568,9 @@ func (v *FieldView) SetStructure(s interface{}) {
 			}
 		}
 	}
-
-	// do more here, recursive, and set changed?
+	if v.snapshot.IsValid() { // only FieldViews made with Build track dirty fields, not table rows
+		v.takeSnapshot()
+	}
 }
 
 func (v *FieldView) CallFieldAction(fieldID string, action ActionType, fieldValue interface{}) {
@@ -465,9 +587,21 @@ func (v *FieldView) CallFieldAction(fieldID string, action ActionType, fieldValu
 	v.callActionHandlerFunc(f, action, fieldValue, &view)
 }
 
+// addEditedHandler makes handler be called with the field after any field of v is edited, not just text fields as handleUpdate.
+// Edits in sub-structs' FieldViews aren't sent to it.
+func (v *FieldView) addEditedHandler(handler func(f *Field)) {
+	v.editedHandlers = append(v.editedHandlers, handler)
+}
+
 func (v *FieldView) callActionHandlerFunc(f *Field, action ActionType, fieldValue interface{}, view *zui.View) bool {
 	if action == EditedAction && f.SetEdited {
-		setFieldViewEdited(v)
+		v.setEdited(f)
+	}
+	if action == EditedAction {
+		v.updateDirtyMarksOfRoot()
+		for _, h := range v.editedHandlers {
+			h(f)
+		}
 	}
 	return callActionHandlerFunc(v.structure, f, action, fieldValue, view)
 }
@@ -726,6 +860,9 @@ func getTextFromNumberishItem(item zreflect.Item, f *Field) string {
 		var t float64
 		if isDurTime {
 			t = ztime.Since(item.Interface.(time.Time))
+			if f.Flags&flagIsCountdown != 0 {
+				t = math.Max(0, -t)
+			}
 		} else {
 			t = ztime.DurSeconds(time.Duration(item.Value.Int()))
 		}
@@ -733,6 +870,10 @@ func getTextFromNumberishItem(item zreflect.Item, f *Field) string {
 		// zlog.Info("makeTextTime:", str, f.Name)
 	}
 	format := f.Format
+	formatter := formatters[format]
+	if formatter != nil {
+		return formatter(item.Value.Interface())
+	}
 	switch format {
 	case "memory":
 		b, err := zint.GetAny(item.Value.Interface())
@@ -755,6 +896,76 @@ func getTextFromNumberishItem(item zreflect.Item, f *Field) string {
 	return fmt.Sprintf(format, item.Value.Interface())
 }
 
+func getEnumForField(f *Field, children []zreflect.Item) zdict.Items {
+	if f.Enum != "" {
+		return fieldEnums[f.Enum]
+	}
+	if f.LocalEnum != "" {
+		ei := findLocalFieldWithID(&children, f.LocalEnum)
+		if ei != nil {
+			getter, _ := ei.Interface.(zdict.ItemsGetter)
+			if getter != nil {
+				return getter.GetItems()
+			}
+		}
+	}
+	return nil
+}
+
+// getItemDisplayString returns the text a field's value is shown as in the ui; enum names, formatted times, durations and units.
+func getItemDisplayString(item zreflect.Item, f *Field, children []zreflect.Item) string {
+	zuistringer, _ := item.Interface.(UIStringer)
+	if zuistringer != nil {
+		return zuistringer.ZUIString()
+	}
+	if f.Kind != zreflect.KindSlice {
+		enum := getEnumForField(f, children)
+		if enum != nil {
+			di := enum.FindValue(item.Interface)
+			if di != nil {
+				return di.Name
+			}
+		}
+	}
+	switch f.Kind {
+	case zreflect.KindBool:
+		return strconv.FormatBool(item.Value.Bool())
+	case zreflect.KindInt:
+		if item.TypeName == "BoolInd" {
+			return fmt.Sprint(item.Interface)
+		}
+		bso, _ := item.Interface.(zbool.BitsetItemsOwner)
+		if bso != nil {
+			var names []string
+			n := item.Value.Int()
+			for _, bs := range bso.GetBitsetItems() {
+				if n&bs.Mask != 0 {
+					names = append(names, bs.Title)
+				}
+			}
+			return strings.Join(names, ", ")
+		}
+		return getTextFromNumberishItem(item, f)
+	case zreflect.KindFloat, zreflect.KindTime:
+		return getTextFromNumberishItem(item, f)
+	case zreflect.KindString:
+		if f.Flags&flagIsFixed != 0 && f.Flags&flagIsImage == 0 {
+			return f.Name
+		}
+		return item.Value.String()
+	case zreflect.KindSlice:
+		getter, _ := item.Interface.(zdict.ItemsGetter)
+		if getter != nil {
+			var names []string
+			for _, di := range getter.GetItems() {
+				names = append(names, di.Name)
+			}
+			return strings.Join(names, ", ")
+		}
+	}
+	return fmt.Sprint(item.Interface)
+}
+
 func (v *FieldView) makeText(item zreflect.Item, f *Field, noUpdate bool) zui.View {
 	// zlog.Info("make Text:", item.FieldName, f.Name, v.structure)
 	str := getTextFromNumberishItem(item, f)
@@ -854,11 +1065,7 @@ func (v *FieldView) updateOldTime(label *zui.Label, f *Field) {
 	val, found := zreflect.FindFieldWithNameInStruct(f.FieldName, v.structure, true)
 	if found {
 		t := val.Interface().(time.Time)
-		if ztime.Since(t) > float64(f.OldSecs) {
-			label.SetColor(zgeo.ColorRed)
-		} else {
-			setColorFromField(label, f)
-		}
+		setColorFromRules(label, f, ztime.Since(t), true) // zero times are very old
 	}
 }
 
@@ -880,10 +1087,21 @@ func (v *FieldView) updateSinceTime(label *zui.Label, f *Field) {
 		var str string
 		t := val.Interface().(time.Time)
 		tooBig := true
+		timeUp := false
 		if !t.IsZero() {
 			// zlog.Info("DUR-FROM:", t)
-			since := time.Since(t)
-			str, tooBig = ztime.GetDurationString(since, f.Flags&flagHasSeconds != 0, f.Flags&flagHasMinutes != 0, f.Flags&flagHasHours != 0, f.FractionDecimals)
+			dur := time.Since(t)
+			if f.Flags&flagIsCountdown != 0 {
+				dur = -dur
+				if dur <= 0 {
+					dur = 0
+					timeUp = true
+				}
+			}
+			str, tooBig = ztime.GetDurationString(dur, f.Flags&flagHasSeconds != 0, f.Flags&flagHasMinutes != 0, f.Flags&flagHasHours != 0, f.FractionDecimals)
+			if f.MaxSecs != 0 && ztime.DurSeconds(dur) > float64(f.MaxSecs) {
+				tooBig = true
+			}
 		}
 		inter := val.Interface()
 		if val.CanAddr() {
@@ -894,7 +1112,18 @@ func (v *FieldView) updateSinceTime(label *zui.Label, f *Field) {
 			label.SetColor(zgeo.ColorRed)
 		} else {
 			label.SetText(str)
-			setColorFromField(label, f)
+			if timeUp {
+				label.SetColor(zgeo.ColorRed)
+			} else {
+				secs := ztime.Since(t)
+				if f.Flags&flagIsCountdown != 0 {
+					secs = -secs // until rules compare the time left
+				}
+				setColorFromRules(label, f, secs, true)
+			}
+		}
+		if timeUp {
+			v.signalTimeUp(f, t, label)
 		}
 		v.callActionHandlerFunc(f, DataChangedAction, inter, &label.View)
 	}
@@ -1027,6 +1256,13 @@ func (v *FieldView) buildStack(name string, defaultAlign zgeo.Alignment, showSta
 	zlog.Assert(reflect.ValueOf(v.structure).Kind() == reflect.Ptr, name, v.structure)
 	// fmt.Println("buildStack1", name, defaultAlign, useMinWidth)
 	children := v.getStructItems()
+	if v.fieldsOrdered {
+		for i := range v.fields {
+			f := &v.fields[i]
+			v.buildItem(f, children[f.Index], f.Index, children, defaultAlign, showStatic, cellMargin, useMinWidth)
+		}
+		return
+	}
 	for j, item := range children {
 		f := findFieldWithIndex(&v.fields, j)
 		if f == nil {
@@ -1142,18 +1378,8 @@ func (v *FieldView) buildItem(f *Field, item zreflect.Item, index int, children
 			if f.IsStatic() {
 				label := view.(*zui.Label)
 				label.Columns = columns
-				if f.Flags&flagIsDuration != 0 || f.OldSecs != 0 {
-					timer := ztimer.RepeatNow(1, func() bool {
-						nlabel := view.(*zui.Label)
-						if f.Flags&flagIsDuration != 0 {
-							v.updateSinceTime(nlabel, f)
-						} else {
-							v.updateOldTime(nlabel, f)
-						}
-						return true
-					})
-					v.AddOnRemoveFunc(timer.Stop)
-					zui.ViewGetNative(view).AddOnRemoveFunc(timer.Stop)
+				if isTimeLabelField(f) {
+					v.registerTimeLabel(label, f)
 				}
 			}
 
@@ -1237,6 +1463,11 @@ func updateItemLocalToolTip(f *Field, children []zreflect.Item, view zui.View) {
 		}
 	} else if f.Tooltip != "" {
 		tip = f.Tooltip
+	} else if f.Flags&flagIsDuration != 0 { // since and until fields show the time they are from or to
+		t, _ := children[f.Index].Interface.(time.Time)
+		if !t.IsZero() {
+			tip = ztime.GetNice(t, true)
+		}
 	}
 	if tip != "" {
 		zui.ViewGetNative(view).SetToolTip(tip)
@@ -1266,9 +1497,12 @@ func (v *FieldView) fieldToDataItem(f *Field, view zui.View, showError bool) (va
 	if f.IsStatic() {
 		return
 	}
+	defer v.lockStructure(true)()
 	children := v.getStructItems()
 	// zlog.Info("fieldViewToDataItem before:", f.Name, f.Index, len(children), "s:", structure)
 	item := children[f.Index]
+	defer v.recordFieldUndo(f, item, deepCopyValue(item.Value))
+	defer v.storeLastValue(f, item) // the view shows the edited value, so Update needn't set it
 	if (f.Enum != "" || f.LocalEnum != "") && !f.IsStatic() {
 		mo, _ := view.(*zui.MenuedShapeView)
 		if mo != nil {
@@ -1310,35 +1544,12 @@ func (v *FieldView) fieldToDataItem(f *Field, view zui.View, showError bool) (va
 			*item.Address.(*bool) = bv.Value().Bool()
 		} else {
 			tv, _ := view.(*zui.TextView)
-			str := tv.Text()
-			if item.Package == "time" && item.TypeName == "Duration" {
-				var secs float64
-				secs, err = ztime.GetSecsFromHMSString(str, f.Flags&flagHasHours != 0, f.Flags&flagHasMinutes != 0, f.Flags&flagHasSeconds != 0)
-				if err != nil {
-					break
-				}
-				d := item.Address.(*time.Duration)
-				if d != nil {
-					*d = ztime.SecondsDur(secs)
-				}
-				return
-			}
-			var i64 int64
-			i64, err = strconv.ParseInt(str, 10, 64)
-			if err != nil {
-				break
-			}
-			zint.SetAny(item.Address, i64)
+			err = setNumberishItemFromString(item, f, tv.Text())
 		}
 
 	case zreflect.KindFloat:
 		tv, _ := view.(*zui.TextView)
-		var f64 float64
-		f64, err = strconv.ParseFloat(tv.Text(), 64)
-		if err != nil {
-			break
-		}
-		zfloat.SetAny(item.Address, f64)
+		err = setNumberishItemFromString(item, f, tv.Text())
 		// zlog.Info("fieldToDataItem float", f.FieldName, view.ObjectName(), tv.Text(), f64, err, item)
 		// fmt.Printf("fieldToDataItem struct: %+v\n", item.Value.Interface())
 
@@ -1374,6 +1585,114 @@ func (v *FieldView) fieldToDataItem(f *Field, view zui.View, showError bool) (va
 	return
 }
 
+// setNumberishItemFromString parses str for int, duration and float items, as entered in a text field.
+func setNumberishItemFromString(item zreflect.Item, f *Field, str string) error {
+	str = strings.TrimSpace(str)
+	if item.Package == "time" && item.TypeName == "Duration" {
+		secs, err := ztime.GetSecsFromHMSString(str, f.Flags&flagHasHours != 0, f.Flags&flagHasMinutes != 0, f.Flags&flagHasSeconds != 0)
+		if err != nil {
+			d, derr := time.ParseDuration(str) // allow raw go durations too
+			if derr != nil {
+				return err
+			}
+			secs = ztime.DurSeconds(d)
+		}
+		d := item.Address.(*time.Duration)
+		if d != nil {
+			*d = ztime.SecondsDur(secs)
+		}
+		return nil
+	}
+	if item.Kind == zreflect.KindFloat {
+		f64, err := strconv.ParseFloat(str, 64)
+		if err != nil {
+			return err
+		}
+		zfloat.SetAny(item.Address, f64)
+		return nil
+	}
+	i64, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		return err
+	}
+	zint.SetAny(item.Address, i64)
+	return nil
+}
+
+func parseTimeForField(str string, f *Field) (time.Time, error) {
+	var t time.Time
+	if str == "" {
+		return t, nil
+	}
+	formats := []string{time.RFC3339Nano, "15:04 02-Jan-06", "15:04:05 02-Jan-06", "2006-01-02 15:04:05", "2006-01-02"}
+	if f.Format != "" && f.Format != "nice" {
+		formats = append([]string{f.Format}, formats...)
+	}
+	var err error
+	for _, format := range formats {
+		t, err = time.ParseInLocation(format, str, time.Local)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return t, err
+}
+
+// setItemFromString sets item from str as shown in the ui or exported; enum names, bools, times, durations and numbers.
+func setItemFromString(item zreflect.Item, f *Field, str string, children []zreflect.Item) error {
+	if f.Kind != zreflect.KindSlice {
+		enum := getEnumForField(f, children)
+		if enum != nil {
+			for _, di := range enum {
+				if zstr.CaselessCompare(di.Name, str) == 0 || fmt.Sprint(di.Value) == str {
+					dval := reflect.ValueOf(di.Value)
+					if !dval.IsValid() {
+						item.Value.Set(reflect.Zero(item.Value.Type()))
+						return nil
+					}
+					if !dval.Type().ConvertibleTo(item.Value.Type()) {
+						return fmt.Errorf("enum value of wrong type: %v", dval.Type())
+					}
+					item.Value.Set(dval.Convert(item.Value.Type()))
+					return nil
+				}
+			}
+			return fmt.Errorf("no such %s: %s", f.Name, str)
+		}
+	}
+	switch f.Kind {
+	case zreflect.KindBool:
+		b := false
+		if str != "" {
+			var err error
+			b, err = strconv.ParseBool(strings.ToLower(strings.TrimSpace(str)))
+			if err != nil {
+				return err
+			}
+		}
+		item.Value.SetBool(b)
+	case zreflect.KindInt:
+		if item.TypeName == "BoolInd" {
+			item.Value.SetInt(int64(zbool.ToBoolInd(zbool.FromString(str, false))))
+			return nil
+		}
+		return setNumberishItemFromString(item, f, str)
+	case zreflect.KindFloat:
+		return setNumberishItemFromString(item, f, str)
+	case zreflect.KindTime:
+		t, err := parseTimeForField(strings.TrimSpace(str), f)
+		if err != nil {
+			return err
+		}
+		item.Value.Set(reflect.ValueOf(t))
+	case zreflect.KindString:
+		item.Value.SetString(str)
+	default:
+		return fmt.Errorf("can't set %v from string", f.Kind)
+	}
+	return nil
+}
+
 func ParentFieldView(view zui.View) *FieldView {
 	for _, nv := range zui.ViewGetNative(view).AllParents() {
 		fv, _ := nv.View.(*FieldView)
@@ -1397,17 +1716,79 @@ func (fv *FieldView) getStructItems() []zreflect.Item {
 	return rootItems.Children
 }
 
+// PresentOKCancelStruct presents a FieldView of structPtr, which is edited directly.
+// On cancel, changed fields are reverted after asking if they should be discarded.
 func PresentOKCancelStruct(structPtr interface{}, params FieldViewParameters, title string, att zui.PresentViewAttributes, done func(ok bool) bool) {
+	presentOKCancelStruct(structPtr, params, title, att, func(ok bool, fview *FieldView) bool {
+		if !ok {
+			fview.Revert()
+		}
+		return done(ok)
+	})
+}
+
+// PresentOKCancelStructCopy presents a FieldView editing a deep copy of structPtr, including sub-structs and slices.
+// The copy's changed fields are only set in structPtr on OK, after ToData and Validate if the structure is a Validator pass.
+// structPtr is locked while copied and set, if it is a sync.Locker or RLocker.
+// done gets structPtr and the ids of the fields that changed, or nil if cancelled.
+func PresentOKCancelStructCopy(structPtr interface{}, params FieldViewParameters, title string, att zui.PresentViewAttributes, done func(ok bool, newValue interface{}, changed []string) bool) {
+	original := reflect.ValueOf(structPtr).Elem()
+	edit := reflect.New(original.Type())
+	unlock := lockStructure(structPtr, false)
+	edit.Elem().Set(deepCopyValue(original))
+	unlock()
+	presentOKCancelStruct(edit.Interface(), params, title, att, func(ok bool, fview *FieldView) bool {
+		if !ok {
+			return done(false, structPtr, nil)
+		}
+		changed := fview.DirtyFields()
+		unlock := lockStructure(structPtr, true)
+		for _, id := range changed { // only changed fields are set, so the struct's own lock isn't replaced by the copy's
+			name := fview.findFieldWithID(id).FieldName
+			original.FieldByName(name).Set(edit.Elem().FieldByName(name))
+		}
+		unlock()
+		return done(true, structPtr, changed)
+	})
+}
+
+func presentOKCancelStruct(structPtr interface{}, params FieldViewParameters, title string, att zui.PresentViewAttributes, done func(ok bool, fview *FieldView) bool) {
 	fview := FieldViewNew("OkCancel", structPtr, params)
 	update := true
 	fview.Build(update, !params.HideStatic)
+	callDone := done
+	done = func(ok bool, fview *FieldView) bool { // the view stops listening for changes when closed, as it isn't always removed
+		closed := callDone(ok, fview)
+		if closed {
+			unregisterChangeListener(fview)
+		}
+		return closed
+	}
 	zui.PresentOKCanceledView(fview, title, att, func(ok bool) bool {
 		if ok {
 			err := fview.ToData(true)
 			if err != nil {
 				return false
 			}
+			validator, _ := structPtr.(Validator)
+			if validator != nil {
+				err = validator.Validate()
+				if err != nil {
+					zui.AlertShowError(err)
+					return false
+				}
+			}
+			return done(true, fview)
 		}
-		return done(ok)
+		if fview.IsDirty() { // ask before throwing away changes, closing it ourselves if ok
+			zui.AlertAsk("Discard changes?", func(discard bool) {
+				if discard && done(false, fview) {
+					dismissed := true
+					zui.PresentViewClose(fview, dismissed, nil)
+				}
+			})
+			return false
+		}
+		return done(false, fview)
 	})
 }
@@ -55,6 +55,7 @@ const (
 	NewStructAction       ActionType = "newstruct"   // called when new stucture is created, for initializing. View may  be nil
 	CreateFieldViewAction ActionType = "createview"  // called to create view, view is pointer to view and is returned in it
 	CreatedViewAction     ActionType = "createdview" // called after view created, view is pointer to newly created view.
+	TimeUpAction          ActionType = "timeup"      // called once when the time of an until field has passed, view is its label
 )
 
 const (
@@ -78,6 +79,10 @@ const (
 	flagIsDuration
 	flagIsOpaque
 	flagIsActions
+	flagIsTreeChildren
+	flagTableHide
+	flagIsOrdered
+	flagIsCountdown
 )
 
 const (
@@ -128,11 +133,14 @@ type Field struct {
 	IsGroup              bool
 	FractionDecimals     int
 	OldSecs              int
+	MaxSecs              int         // MaxSecs makes since and until fields show a red ● if longer, as they do if too long to show
+	ColorRules           []ColorRule // ColorRules color the field's view from its value, set with colorif and oldsecs tags
 	ValueStoreKey        string
 	Visible              bool
 	Disabled             bool
 	SetEdited            bool
 	WidgetName           string
+	Total                string // sum, avg, min, max or count; shown in a TableView's footer
 }
 
 type ActionHandler interface {
@@ -143,12 +151,25 @@ type ActionFieldHandler interface {
 	HandleFieldAction(f *Field, action ActionType, view *zui.View) bool
 }
 
-var widgeters = map[string]Widgeter{}
+// Validator is implemented by structures that check their values before being accepted in PresentOKCancelStruct.
+type Validator interface {
+	Validate() error
+}
+
+var (
+	widgeters  = map[string]Widgeter{}
+	formatters = map[string]func(val interface{}) string{}
+)
 
 func RegisterWigeter(name string, w Widgeter) {
 	widgeters[name] = w
 }
 
+// RegisterFormatter registers a function used to make a string from a value for fields with format:name tag.
+func RegisterFormatter(name string, formatter func(val interface{}) string) {
+	formatters[name] = formatter
+}
+
 func (f Field) IsStatic() bool {
 	return f.Flags&flagIsStatic != 0
 }
@@ -311,6 +332,16 @@ func (f *Field) makeFromReflectItem(structure interface{}, item zreflect.Item, i
 			f.Flags |= flagHasSeconds
 		case "oldsecs":
 			f.OldSecs = int(n)
+			f.ColorRules = append(f.ColorRules, ColorRule{Value: n, Color: zgeo.ColorRed})
+		case "colorif":
+			rules, err := parseColorRules(val)
+			if err != nil {
+				zlog.Error(err, "colorif", f.Name)
+				break
+			}
+			f.ColorRules = append(f.ColorRules, rules...)
+		case "maxsecs":
+			f.MaxSecs = int(n)
 		case "mins":
 			f.Flags |= flagHasMinutes
 		case "hours":
@@ -459,6 +490,21 @@ func (f *Field) makeFromReflectItem(structure interface{}, item zreflect.Item, i
 			}
 		case "since":
 			f.Flags |= flagIsStatic | flagIsDuration
+		case "until":
+			f.Flags |= flagIsStatic | flagIsDuration | flagIsCountdown
+		case "children":
+			f.Flags |= flagIsTreeChildren
+		case "tablehide":
+			f.Flags |= flagTableHide
+		case "ordered":
+			f.Flags |= flagIsOrdered
+		case "total":
+			switch val {
+			case "sum", "avg", "min", "max", "count":
+				f.Total = val
+			default:
+				zlog.Error(nil, "bad total:", val, f.FieldName)
+			}
 		}
 	}
 	if immediateEdit {
@@ -692,14 +738,15 @@ func SortSliceWithFields(slice interface{}, fields []Field, sortOrder []zui.Sort
 	// fmt.Printf("FieldMap: %+v %+v\n", fieldMap, sortOrder)
 	// zlog.Info("SORT:", sortOrder, enumTitles)
 	val := reflect.ValueOf(slice)
-	sort.SliceStable(slice, func(i, j int) bool {
-		ei := val.Index(i).Addr().Interface()
-		ej := val.Index(j).Addr().Interface()
-		ic, ierr := zreflect.ItterateStruct(ei, zreflect.Options{UnnestAnonymous: true})
-		jc, jerr := zreflect.ItterateStruct(ej, zreflect.Options{UnnestAnonymous: true})
+	sort.Stable(rowSorter{slice: val, less: func(i, j int) bool {
+		ic, ierr := itterateRowLocked(val, i)
+		jc, jerr := itterateRowLocked(val, j)
 		zlog.Assert(ierr == nil && jerr == nil, ierr, jerr)
 		for _, s := range sortOrder {
 			f := fieldMap[s.ID]
+			if f == nil { // not a field, like a TableView's tree column
+				continue
+			}
 			// zlog.Info("SORTING:", i, j, s.ID, f != nil)
 			iitem := ic.Children[f.Index]
 			jitem := jc.Children[f.Index]
@@ -765,7 +812,7 @@ func SortSliceWithFields(slice interface{}, fields []Field, sortOrder []zui.Sort
 		}
 		// zlog.Fatal(nil, "No sort fields set for struct")
 		return false
-	})
+	}})
 	// zlog.Info("SORT TIME:", time.Since(start))
 }
 
@@ -0,0 +1,194 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/torlangballe/zui"
+	"github.com/torlangballe/zutil/zreflect"
+	"github.com/torlangballe/zutil/zstr"
+)
+
+// ImportError is a problem parsing a cell, or finding the field of a header cell, when importing.
+type ImportError struct {
+	Row    int // Row is the line in the input, starting at 1 with the header
+	Column int
+	Title  string
+	Err    error
+}
+
+func (e ImportError) Error() string {
+	return fmt.Sprintf("row %d, column %s: %v", e.Row, e.Title, e.Err)
+}
+
+func findImportField(fields []Field, title string) *Field {
+	title = strings.TrimSpace(title)
+	for i, f := range fields {
+		for _, name := range []string{f.ID, f.FieldName, f.Name, f.Title} {
+			if name != "" && zstr.CaselessCompare(name, title) == 0 {
+				return &fields[i]
+			}
+		}
+	}
+	return nil
+}
+
+// ImportSlice parses CSV or TSV from r, where the header row has the names, titles or ids of fields, appending a struct to the slice slicePtr points to for each row.
+// Cells are parsed as if entered in the ui, so enum names, durations and formatted times can be used.
+// It returns an error for each cell that couldn't be set, with err being non-nil only if the input couldn't be read at all.
+func ImportSlice(r io.Reader, slicePtr interface{}, format ExportFormat) (rowErrors []ImportError, err error) {
+	sliceVal := tableGetSliceRValFromPointer(slicePtr)
+	if sliceVal.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("ImportSlice: not pointer to slice: %v", reflect.ValueOf(slicePtr).Kind())
+	}
+	records, err := readImportRecords(r, format)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+	elementType := sliceVal.Type().Elem()
+	fields := makeTableFields(reflect.New(elementType).Interface())
+	columns, rowErrors := getImportColumns(fields, records[0])
+	for n, record := range records[1:] {
+		ptr := newImportStruct(elementType)
+		errs, err := setStructFromRecord(ptr.Interface(), columns, records[0], record, n+2)
+		if err != nil {
+			return rowErrors, err
+		}
+		rowErrors = append(rowErrors, errs...)
+		sliceVal.Set(reflect.Append(sliceVal, ptr.Elem()))
+	}
+	return rowErrors, nil
+}
+
+func readImportRecords(r io.Reader, format ExportFormat) ([][]string, error) {
+	cr := csv.NewReader(r)
+	switch format {
+	case ExportCSV:
+	case ExportTSV:
+		cr.Comma = '\t'
+		cr.LazyQuotes = true
+	default:
+		return nil, fmt.Errorf("can't import format: %s", format)
+	}
+	cr.FieldsPerRecord = -1
+	return cr.ReadAll()
+}
+
+// getImportColumns returns the field of each column in header, or nil with an error for columns without one.
+func getImportColumns(fields []Field, header []string) (columns []*Field, rowErrors []ImportError) {
+	columns = make([]*Field, len(header))
+	for i, title := range header {
+		columns[i] = findImportField(fields, title)
+		if columns[i] == nil {
+			rowErrors = append(rowErrors, ImportError{Row: 1, Column: i, Title: title, Err: fmt.Errorf("no field for column")})
+		}
+	}
+	return columns, rowErrors
+}
+
+// newImportStruct returns a pointer to a new struct of structType, calling NewStructAction to initialize it.
+func newImportStruct(structType reflect.Type) reflect.Value {
+	ptr := reflect.New(structType)
+	ah, _ := ptr.Interface().(ActionHandler)
+	if ah != nil {
+		ah.HandleAction(nil, NewStructAction, nil)
+	}
+	return ptr
+}
+
+// setStructFromRecord sets the fields of the struct ptr points to from the cells of record, which is line row in the input.
+func setStructFromRecord(ptr interface{}, columns []*Field, header, record []string, row int) (rowErrors []ImportError, err error) {
+	root, err := zreflect.ItterateStruct(ptr, zreflect.Options{UnnestAnonymous: true})
+	if err != nil {
+		return nil, err
+	}
+	for i, str := range record {
+		if i >= len(columns) || columns[i] == nil {
+			continue
+		}
+		f := columns[i]
+		item := root.Children[f.Index]
+		err := setItemFromString(item, f, str, root.Children)
+		if err != nil {
+			rowErrors = append(rowErrors, ImportError{Row: row, Column: i, Title: header[i], Err: err})
+		}
+	}
+	return rowErrors, nil
+}
+
+func getSliceIDIndexes(getter zui.ListViewIDGetter, count int) map[string]int {
+	m := map[string]int{}
+	for i := 0; i < count; i++ {
+		m[getter.GetID(i)] = i
+	}
+	return m
+}
+
+// ImportWithPreview parses CSV or TSV from r, and shows the rows in a table to confirm before merging them in.
+// Rows with the same id as an existing row replace it, others are added. done is called with merged true if it was.
+func (v *TableView) ImportWithPreview(r io.Reader, format ExportFormat, att zui.PresentViewAttributes, done func(merged bool)) {
+	val := tableGetSliceRValFromPointer(v.structure)
+	importedPtr := reflect.New(val.Type())
+	importedPtr.Elem().Set(reflect.MakeSlice(val.Type(), 0, 0))
+	rowErrors, err := ImportSlice(r, importedPtr.Interface(), format)
+	if err != nil {
+		zui.AlertShowError(err)
+		if done != nil {
+			done(false)
+		}
+		return
+	}
+	imported := importedPtr.Elem()
+	oldIndexes := getSliceIDIndexes(getSliceIDGetter(val.Interface()), val.Len())
+	importedGetter := getSliceIDGetter(imported.Interface())
+	var changed int
+	for i := 0; i < imported.Len(); i++ {
+		_, got := oldIndexes[importedGetter.GetID(i)]
+		if got && hasRowKeys(val) {
+			changed++
+		}
+	}
+	title := fmt.Sprintf("Import %d new and %d changed rows", imported.Len()-changed, changed)
+	if len(rowErrors) != 0 {
+		title += fmt.Sprintf(" (%d errors, first: %v)", len(rowErrors), rowErrors[0])
+	}
+	preview := TableViewNew(v.ObjectName()+".import", v.Header != nil, importedPtr.Interface())
+	zui.PresentOKCanceledView(preview, title, att, func(ok bool) bool {
+		if ok {
+			v.mergeRows(imported)
+		}
+		if done != nil {
+			done(ok)
+		}
+		return true
+	})
+}
+
+// mergeRows replaces rows in the table's slice with rows in rows with the same id, and appends the others.
+func (v *TableView) mergeRows(rows reflect.Value) {
+	val := tableGetSliceRValFromPointer(v.structure)
+	oldSlice := val.Interface()
+	nval := reflect.MakeSlice(val.Type(), val.Len(), val.Len()+rows.Len())
+	copyRowsLocked(nval, val)
+	indexes := getSliceIDIndexes(getSliceIDGetter(oldSlice), val.Len())
+	getter := getSliceIDGetter(rows.Interface())
+	for i := 0; i < rows.Len(); i++ {
+		index, got := indexes[getter.GetID(i)]
+		if got && hasRowKeys(val) { // rows with index ids are always new
+			nval.Index(index).Set(rows.Index(i))
+			continue
+		}
+		nval = reflect.Append(nval, rows.Index(i))
+	}
+	val.Set(nval)
+	v.UpdateWithOldNewSlice(oldSlice, nval.Interface())
+}
@@ -0,0 +1,139 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"reflect"
+	"sync"
+	"unsafe"
+
+	"github.com/torlangballe/zutil/zreflect"
+)
+
+// RLocker is implemented by structures changed in other goroutines that can be locked for reading, like a sync.RWMutex.
+// Structures that are a sync.Locker are locked with Lock when read if they aren't an RLocker.
+// FieldViews lock their structure while updating from it and writing edits to it.
+// TableViews lock each row while reading, sorting, copying and pasting into it, as rows are the structures of their row FieldViews.
+// Rows are copied to new slices when sorted, moved and pasted, so they should embed a *sync.RWMutex, not a sync.RWMutex, so copies share the lock.
+// Rows sorted in place have their values swapped, but their locks stay, and rows are only locked one at a time, so they can share a lock.
+// Action handlers called while locked mustn't lock the structure themselves.
+type RLocker interface {
+	RLock()
+	RUnlock()
+}
+
+// lockStructure locks structure if it is a sync.Locker, or an RLocker if not write, returning a function to unlock it.
+func lockStructure(structure interface{}, write bool) (unlock func()) {
+	if !write {
+		rl, _ := structure.(RLocker)
+		if rl != nil {
+			rl.RLock()
+			return rl.RUnlock
+		}
+	}
+	l, _ := structure.(sync.Locker)
+	if l != nil {
+		l.Lock()
+		return l.Unlock
+	}
+	return func() {}
+}
+
+// lockStructure locks the structure of v's outermost FieldView, unless it is already locked by a FieldView in it.
+func (v *FieldView) lockStructure(write bool) (unlock func()) {
+	root := v
+	for root.parent != nil {
+		root = root.parent
+	}
+	if root.lockDepth > 0 {
+		return func() {}
+	}
+	u := lockStructure(root.structure, write)
+	root.lockDepth++
+	return func() {
+		root.lockDepth--
+		u()
+	}
+}
+
+// lockRow locks the struct at index i of slice, returning a function to unlock it.
+func lockRow(slice reflect.Value, i int, write bool) (unlock func()) {
+	return lockStructure(slice.Index(i).Addr().Interface(), write)
+}
+
+// copyRowsLocked copies the rows of src to dst like reflect.Copy, read-locking each row while it is copied.
+func copyRowsLocked(dst, src reflect.Value) {
+	n := src.Len()
+	if dst.Len() < n {
+		n = dst.Len()
+	}
+	for i := 0; i < n; i++ {
+		unlock := lockRow(src, i, false)
+		dst.Index(i).Set(src.Index(i))
+		unlock()
+	}
+}
+
+// rowSorter is a sort.Interface for a slice of structs, locking rows one at a time while they are read or set,
+// as rows might share a lock, like a parent's mutex. less must lock the rows it reads itself, see itterateRowLocked.
+type rowSorter struct {
+	slice reflect.Value
+	less  func(i, j int) bool
+}
+
+func (s rowSorter) Len() int {
+	return s.slice.Len()
+}
+
+func (s rowSorter) Less(i, j int) bool {
+	return s.less(i, j)
+}
+
+// Swap swaps the values of rows i and j, leaving their locks in place, so a row's lock is unlocked after it is set.
+func (s rowSorter) Swap(i, j int) {
+	if i == j {
+		return
+	}
+	ri := reflect.New(s.slice.Type().Elem()).Elem()
+	rj := reflect.New(s.slice.Type().Elem()).Elem()
+	unlock := lockRow(s.slice, i, false)
+	ri.Set(s.slice.Index(i))
+	unlock()
+	unlock = lockRow(s.slice, j, false)
+	rj.Set(s.slice.Index(j))
+	unlock()
+	unlock = lockRow(s.slice, i, true)
+	setValueKeepingLocks(s.slice.Index(i), rj)
+	unlock()
+	unlock = lockRow(s.slice, j, true)
+	setValueKeepingLocks(s.slice.Index(j), ri)
+	unlock()
+}
+
+// setValueKeepingLocks sets dst to src, except fields of structs in it that are mutexes or pointers to them.
+// Unexported fields are set too, as they are when setting a whole struct.
+func setValueKeepingLocks(dst, src reflect.Value) {
+	if dst.Kind() != reflect.Struct {
+		dst.Set(src)
+		return
+	}
+	for i := 0; i < dst.NumField(); i++ {
+		field := dst.Field(i)
+		t := field.Type()
+		if isLockType(t) || (t.Kind() == reflect.Ptr && isLockType(t.Elem())) {
+			continue
+		}
+		if !field.CanSet() {
+			field = reflect.NewAt(t, unsafe.Pointer(field.UnsafeAddr())).Elem()
+		}
+		setValueKeepingLocks(field, src.Field(i))
+	}
+}
+
+// itterateRowLocked iterates the struct at index i of slice while it is read-locked.
+// The items' Interface values are copies, so can be compared after it is unlocked.
+func itterateRowLocked(slice reflect.Value, i int) (zreflect.Item, error) {
+	defer lockRow(slice, i, false)()
+	return zreflect.ItterateStruct(slice.Index(i).Addr().Interface(), zreflect.Options{UnnestAnonymous: true})
+}
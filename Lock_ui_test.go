@@ -0,0 +1,155 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/torlangballe/zui"
+)
+
+type lockedRow struct {
+	ID    string `zui:"id"`
+	Count int    `zui:"static"`
+	Name  string `zui:"static"`
+	lock  *sync.RWMutex
+}
+
+func (r *lockedRow) Lock()    { r.lock.Lock() }
+func (r *lockedRow) Unlock()  { r.lock.Unlock() }
+func (r *lockedRow) RLock()   { r.lock.RLock() }
+func (r *lockedRow) RUnlock() { r.lock.RUnlock() }
+
+func makeLockedRows(n int) []lockedRow {
+	rows := make([]lockedRow, n)
+	for i := range rows {
+		rows[i] = lockedRow{ID: fmt.Sprint(i), Count: n - i, lock: &sync.RWMutex{}}
+	}
+	return rows
+}
+
+// writeRows changes rows under their locks, as a poller goroutine would, until stop is closed.
+func writeRows(rows []lockedRow, stop chan struct{}, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for n := 0; ; n++ {
+		select {
+		case <-stop:
+			return
+		default:
+		}
+		r := &rows[n%len(rows)]
+		r.Lock()
+		r.Count = n
+		r.Name = fmt.Sprint("row ", n)
+		r.Unlock()
+	}
+}
+
+// TestUpdateWhileWriting updates a FieldView of a row while it is written to in another goroutine.
+// Run with -race to check all reads are locked.
+func TestUpdateWhileWriting(t *testing.T) {
+	rows := makeLockedRows(10)
+	fv := FieldViewNew("row", &rows[0], FieldViewParametersDefault())
+	fv.Build(true, true)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go writeRows(rows, stop, &wg)
+	end := time.Now().Add(time.Second / 2)
+	for time.Now().Before(end) {
+		fv.Update(false)
+	}
+	close(stop)
+	wg.Wait()
+}
+
+// TestSortCopyWhileWriting copies and sorts rows, as a table's header does, while they are written to in another goroutine.
+func TestSortCopyWhileWriting(t *testing.T) {
+	rows := makeLockedRows(200)
+	fields := makeTableFields(&rows[0])
+	order := []zui.SortInfo{{ID: "count", SmallFirst: true}}
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go writeRows(rows, stop, &wg)
+	val := reflect.ValueOf(rows)
+	for i := 0; i < 20; i++ {
+		nval := reflect.MakeSlice(val.Type(), val.Len(), val.Len())
+		copyRowsLocked(nval, val)
+		sorted := nval.Interface().([]lockedRow)
+		SortSliceWithFields(sorted, fields, order)
+		for j := 1; j < len(sorted); j++ {
+			if sorted[j-1].Count > sorted[j].Count {
+				t.Fatal("not sorted at", j, sorted[j-1].Count, sorted[j].Count)
+			}
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
+
+// TestRowIDsWhileWriting reads row ids and texts while rows are written to in another goroutine.
+func TestRowIDsWhileWriting(t *testing.T) {
+	rows := makeLockedRows(100)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go writeRows(rows, stop, &wg)
+	fields := makeTableFields(&rows[0])
+	for i := 0; i < 50; i++ {
+		ids := getSliceIDGetter(rows)
+		if getIDGetterCount(ids) != len(rows) {
+			t.Fatal("wrong id count")
+		}
+		var str strings.Builder
+		err := exportRows(&str, fields, []interface{}{&rows[i]}, ExportTSV, ExportOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+}
+
+// sharedLockRow is a row locked with its table's mutex, as rows locking a parent are.
+type sharedLockRow struct {
+	ID     string `zui:"id"`
+	Count  int    `zui:"static"`
+	parent *sync.RWMutex
+}
+
+func (r *sharedLockRow) Lock()    { r.parent.Lock() }
+func (r *sharedLockRow) Unlock()  { r.parent.Unlock() }
+func (r *sharedLockRow) RLock()   { r.parent.RLock() }
+func (r *sharedLockRow) RUnlock() { r.parent.RUnlock() }
+
+// TestSortSharedLock sorts rows sharing one lock, which deadlocks if two rows are locked at once.
+func TestSortSharedLock(t *testing.T) {
+	lock := &sync.RWMutex{}
+	rows := make([]sharedLockRow, 100)
+	for i := range rows {
+		rows[i] = sharedLockRow{ID: fmt.Sprint(i), Count: len(rows) - i, parent: lock}
+	}
+	fields := makeTableFields(&rows[0])
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		SortSliceWithFields(rows, fields, []zui.SortInfo{{ID: "count", SmallFirst: true}})
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("sorting rows sharing a lock deadlocked")
+	}
+	for i := 1; i < len(rows); i++ {
+		if rows[i-1].Count > rows[i].Count {
+			t.Fatal("not sorted at", i, rows[i-1].Count, rows[i].Count)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/torlangballe/zui"
+	"github.com/torlangballe/zutil/ztimer"
+)
+
+// Notifier is implemented by structures that know when they change, typically in a background goroutine.
+// Views showing one call SetChangedHandler with a function to call after changing, which can be called from any goroutine.
+// A TableView calls it for rows that are Notifiers when they are shown.
+type Notifier interface {
+	SetChangedHandler(changed func())
+}
+
+type changeListener struct {
+	matches func(ptr interface{}) bool // matches returns true if the listener shows what ptr points to
+	changed func(ptrs []interface{})   // changed is called with the matching pointers that changed
+}
+
+var ChangedCoalesceSecs = 0.1 // ChangedCoalesceSecs is how long after Changed views are updated, so many changes cause one update
+
+var changes = struct {
+	sync.Mutex
+	listeners map[zui.View]changeListener
+	pending   []interface{}
+}{listeners: map[zui.View]changeListener{}}
+
+// Changed tells FieldViews and TableViews showing the structure or slice ptr points to that it has changed, so they are updated.
+// For a TableView, ptr can also point to a row in its slice, or in its tree.
+// It can be called from any goroutine. Changes are coalesced, and views are updated on the UI loop ChangedCoalesceSecs later.
+func Changed(ptr interface{}) {
+	changes.Lock()
+	defer changes.Unlock()
+	for _, p := range changes.pending {
+		if p == ptr {
+			return
+		}
+	}
+	changes.pending = append(changes.pending, ptr)
+	if len(changes.pending) == 1 {
+		ztimer.StartIn(ChangedCoalesceSecs, flushChanges)
+	}
+}
+
+func flushChanges() {
+	changes.Lock()
+	pending := changes.pending
+	changes.pending = nil
+	listeners := make([]changeListener, 0, len(changes.listeners))
+	for _, l := range changes.listeners {
+		listeners = append(listeners, l)
+	}
+	changes.Unlock()
+	for _, l := range listeners {
+		var ptrs []interface{}
+		for _, p := range pending {
+			if l.matches(p) {
+				ptrs = append(ptrs, p)
+			}
+		}
+		if len(ptrs) != 0 {
+			l.changed(ptrs)
+		}
+	}
+}
+
+// registerChangeListener makes view be updated with changed when Changed is called with a pointer matches returns true for.
+// Views must call unregisterChangeListener when removed or closed, as they are held until then. If structure is a Notifier, it is set to call Changed.
+func registerChangeListener(view zui.View, structure interface{}, matches func(ptr interface{}) bool, changed func(ptrs []interface{})) {
+	changes.Lock()
+	changes.listeners[view] = changeListener{matches: matches, changed: changed}
+	changes.Unlock()
+	setNotifierHandler(structure)
+}
+
+// setNotifierHandler makes structure call Changed with itself when it changes, if it is a Notifier.
+// Table rows are set as they are bound to row views, so a row that is a Notifier updates its row while shown.
+func setNotifierHandler(structure interface{}) {
+	notifier, _ := structure.(Notifier)
+	if notifier != nil {
+		notifier.SetChangedHandler(func() {
+			Changed(structure)
+		})
+	}
+}
+
+// unregisterChangeListener stops view being updated by Changed, so it isn't held by the listeners.
+func unregisterChangeListener(view zui.View) {
+	changes.Lock()
+	delete(changes.listeners, view)
+	changes.Unlock()
+}
+
+// listenForChanges makes a FieldView showing its own structure update when it is changed.
+func (v *FieldView) listenForChanges() {
+	registerChangeListener(v, v.structure, func(ptr interface{}) bool {
+		return ptr == v.structure
+	}, func(ptrs []interface{}) {
+		dontOverwriteEdited := true
+		v.Update(dontOverwriteEdited)
+	})
+}
+
+// listenForChanges makes the table update its rows when its slice is changed, or flush rows in it that are changed.
+func (v *TableView) listenForChanges() {
+	registerChangeListener(v, v.structure, func(ptr interface{}) bool {
+		return ptr == v.structure || v.findRowIDOfPointer(tableGetSliceRValFromPointer(v.structure), ptr, "") != ""
+	}, func(ptrs []interface{}) {
+		for _, p := range ptrs {
+			if p == v.structure {
+				if v.Header != nil {
+					v.sortSlice(tableGetSliceRValFromPointer(v.structure).Interface())
+				}
+				v.clearMeasuredRowHeights()
+				v.Reload()
+				v.rowsUpdated()
+				return
+			}
+		}
+		for _, p := range ptrs {
+			i := v.getRowIndexFromID(v.findRowIDOfPointer(tableGetSliceRValFromPointer(v.structure), p, ""))
+			if i != -1 {
+				edited := false
+				v.FlushDataToRow(i, edited)
+			}
+		}
+		v.updateFooter()
+	})
+}
+
+// findRowIDOfPointer returns the id of the row ptr points to in slice, or its tree children, or "" if it isn't in it.
+// Rows are compared by address, and only locked while their ids and children are read.
+func (v *TableView) findRowIDOfPointer(slice reflect.Value, ptr interface{}, parentID string) string {
+	var ids zui.ListViewIDGetter // made when needed, with the same ids as the table's rows
+	getID := func(i int) string {
+		if ids == nil {
+			ids = getTreeRowIDs(slice, parentID)
+		}
+		return ids.GetID(i)
+	}
+	for i := 0; i < slice.Len(); i++ {
+		rval := slice.Index(i)
+		if rval.Addr().Interface() == ptr {
+			return getID(i)
+		}
+		if v.treeFieldName != "" {
+			unlock := lockRow(slice, i, false)
+			children := rval.FieldByName(v.treeFieldName)
+			unlock() // before looking in children, as they might share its lock
+			if children.Len() == 0 {
+				continue
+			}
+			id := v.findRowIDOfPointer(children, ptr, getID(i))
+			if id != "" {
+				return id
+			}
+		}
+	}
+	return ""
+}
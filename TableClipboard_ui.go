@@ -0,0 +1,133 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/torlangballe/zui"
+	"github.com/torlangballe/zutil/zreflect"
+)
+
+func (v *TableView) handleClipboardKey(key zui.KeyboardKey, mods zui.KeyboardModifier) bool {
+	if v.cursor.editing || (mods != zui.KeyboardModifierControl && mods != zui.KeyboardModifierCommand) {
+		return false
+	}
+	switch key {
+	case 'c', 'C':
+		if v.CopyRows {
+			v.CopySelectedRows()
+			return true
+		}
+	case 'v', 'V':
+		if v.PasteRows {
+			rowErrors, err := v.PasteTSV(zui.ClipboardGetString())
+			if err == nil && len(rowErrors) != 0 {
+				err = fmt.Errorf("%d cells couldn't be pasted, first: %v", len(rowErrors), rowErrors[0])
+			}
+			if err != nil {
+				zui.AlertShowError(err)
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// CopySelectedRows copies the selected row to the clipboard as TSV, with the text shown in each column and a header line.
+func (v *TableView) CopySelectedRows() {
+	i := v.List.SelectionIndex()
+	if i == -1 || v.isDetailRow(i) {
+		return
+	}
+	var str strings.Builder
+	err := exportRows(&str, v.fields, []interface{}{v.GetRowData(i)}, ExportTSV, ExportOptions{})
+	if err != nil {
+		zui.AlertShowError(err)
+		return
+	}
+	zui.ClipboardSetString(str.String())
+}
+
+// PasteTSV parses text as TSV with a header line, as copied with CopySelectedRows.
+// Rows with the id of an existing row update its non-static fields, sending EditedAction for each that changed.
+// Other rows are added, with NewStructAction sent before their fields are set.
+func (v *TableView) PasteTSV(text string) (rowErrors []ImportError, err error) {
+	records, err := readImportRecords(strings.NewReader(text), ExportTSV)
+	if err != nil || len(records) < 2 {
+		return nil, err
+	}
+	header := records[0]
+	columns, rowErrors := getImportColumns(v.allFields, header)
+	val := tableGetSliceRValFromPointer(v.structure)
+	oldSlice := val.Interface()
+	nval := reflect.MakeSlice(val.Type(), val.Len(), val.Len()+len(records)-1)
+	copyRowsLocked(nval, val)
+	indexes := getSliceIDIndexes(getSliceIDGetter(oldSlice), val.Len())
+	one := reflect.MakeSlice(val.Type(), 1, 1) // used to get the id of a parsed row
+	var editedIDs []string
+	for n, record := range records[1:] {
+		row := n + 2
+		parsed := reflect.New(val.Type().Elem())
+		errs, err := setStructFromRecord(parsed.Interface(), columns, header, record, row)
+		if err != nil {
+			return rowErrors, err
+		}
+		one.Index(0).Set(parsed.Elem())
+		id := getSliceRowID(one, 0)
+		index, got := indexes[id]
+		if got && hasRowKeys(val) { // rows with index ids are always new
+			changed, errs := pasteIntoRow(nval.Index(index).Addr().Interface(), columns, header, record, row)
+			rowErrors = append(rowErrors, errs...)
+			if changed {
+				editedIDs = append(editedIDs, id)
+			}
+			continue
+		}
+		rowErrors = append(rowErrors, errs...)
+		ptr := newImportStruct(val.Type().Elem())
+		setStructFromRecord(ptr.Interface(), columns, header, record, row) // errors are the same as when parsed above
+		nval = reflect.Append(nval, ptr.Elem())
+	}
+	val.Set(nval)
+	v.UpdateWithOldNewSlice(oldSlice, nval.Interface())
+	for _, id := range editedIDs {
+		i := v.getRowIndexFromID(id)
+		if i != -1 {
+			v.FlushDataToRow(i, true)
+		}
+	}
+	return rowErrors, nil
+}
+
+// pasteIntoRow sets the non-static fields of rowPtr from the cells of record whose text differs from what is shown, sending EditedAction for each.
+func pasteIntoRow(rowPtr interface{}, columns []*Field, header, record []string, row int) (changed bool, rowErrors []ImportError) {
+	root, err := zreflect.ItterateStruct(rowPtr, zreflect.Options{UnnestAnonymous: true})
+	if err != nil {
+		return false, nil
+	}
+	for i, str := range record {
+		if i >= len(columns) || columns[i] == nil || columns[i].IsStatic() {
+			continue
+		}
+		f := columns[i]
+		item := root.Children[f.Index]
+		unlock := lockStructure(rowPtr, true)
+		if getItemDisplayString(item, f, root.Children) == str {
+			unlock()
+			continue
+		}
+		err := setItemFromString(item, f, str, root.Children)
+		unlock()
+		if err != nil {
+			rowErrors = append(rowErrors, ImportError{Row: row, Column: i, Title: header[i], Err: err})
+			continue
+		}
+		changed = true
+		callActionHandlerFunc(rowPtr, f, EditedAction, item.Value.Interface(), nil)
+	}
+	return changed, rowErrors
+}
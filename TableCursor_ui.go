@@ -0,0 +1,251 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"github.com/torlangballe/zui"
+	"github.com/torlangballe/zutil/zbool"
+	"github.com/torlangballe/zutil/zgeo"
+	"github.com/torlangballe/zutil/zint"
+)
+
+var TableDefaultCursorColor = zgeo.ColorNew(0.2, 0.5, 1, 1)
+
+// tableCursor is the cell with keyboard focus when TableView.CellNavigation is set.
+// It is kept by row id, so it follows its row when sorted or updated.
+type tableCursor struct {
+	rowID       string
+	column      int    // column is the index in TableView.fields
+	editing     bool   // editing is set while the cursor's text cell is being edited
+	editingText string // editingText is the text when editing started, restored with escape
+}
+
+func (v *TableView) setupKeyHandler() {
+	v.SetCanFocus(true)
+	v.SetKeyHandler(func(key zui.KeyboardKey, mods zui.KeyboardModifier) bool {
+		if v.handleClipboardKey(key, mods) || v.handleOrderKey(key, mods) {
+			return true
+		}
+		if v.CellNavigation {
+			return v.handleCursorKey(key, mods)
+		}
+		return false
+	})
+}
+
+// CellCursor returns the row id and field id of the cell with the cursor, which are empty if there is none.
+func (v *TableView) CellCursor() (rowID, fieldID string) {
+	if v.cursor.rowID == "" || v.cursor.column >= len(v.fields) {
+		return "", ""
+	}
+	return v.cursor.rowID, v.fields[v.cursor.column].ID
+}
+
+// SetCellCursor moves the cursor to the cell in row rowID, with field fieldID, selecting the row.
+func (v *TableView) SetCellCursor(rowID, fieldID string) {
+	for i, f := range v.fields {
+		if f.ID == fieldID {
+			v.moveCursor(rowID, i)
+			return
+		}
+	}
+}
+
+func (v *TableView) getCursorCellView(rowID string, column int) (*FieldView, zui.View) {
+	i := v.getRowIndexFromID(rowID)
+	if i == -1 || column >= len(v.fields) {
+		return nil, nil
+	}
+	fv, _ := v.List.GetVisibleRowViewFromIndex(i).(*FieldView)
+	if fv == nil {
+		return nil, nil
+	}
+	view, _ := fv.findNamedViewOrInLabelized(v.fields[column].ID)
+	return fv, view
+}
+
+func (v *TableView) showCursorInCell(rowID string, column int, show bool) {
+	_, view := v.getCursorCellView(rowID, column)
+	showCursorInView(view, show)
+}
+
+// showCursorInNewRow shows the cursor in a newly created row view, if it has it.
+func (v *TableView) showCursorInNewRow(fv *FieldView, rowID string) {
+	if rowID == "" || rowID != v.cursor.rowID || v.cursor.column >= len(v.fields) {
+		return
+	}
+	view, _ := fv.findNamedViewOrInLabelized(v.fields[v.cursor.column].ID)
+	showCursorInView(view, true)
+}
+
+func showCursorInView(view zui.View, show bool) {
+	if view == nil {
+		return
+	}
+	if show {
+		zui.ViewGetNative(view).SetStroke(2, TableDefaultCursorColor)
+	} else {
+		zui.ViewGetNative(view).SetStroke(0, zgeo.Color{})
+	}
+}
+
+// updateCursor shows the cursor again after rows have been updated or re-created.
+func (v *TableView) updateCursor() {
+	if v.cursor.rowID != "" {
+		v.showCursorInCell(v.cursor.rowID, v.cursor.column, true)
+	}
+}
+
+func (v *TableView) moveCursor(rowID string, column int) {
+	if v.cursor.editing {
+		v.commitCellEdit()
+	}
+	v.showCursorInCell(v.cursor.rowID, v.cursor.column, false)
+	v.cursor.rowID = rowID
+	v.cursor.column = column
+	i := v.getRowIndexFromID(rowID)
+	if i != -1 && v.List.SelectionIndex() != i {
+		v.List.Select(i, true, false)
+	}
+	v.showCursorInCell(rowID, column, true)
+}
+
+func (v *TableView) moveCursorBy(rows, columns int) {
+	count := v.GetRowCount()
+	if count == 0 || len(v.fields) == 0 {
+		return
+	}
+	i := v.getRowIndexFromID(v.cursor.rowID)
+	if i == -1 {
+		v.moveCursor(v.getRowID(0), 0)
+		return
+	}
+	col := v.cursor.column + columns
+	if col >= len(v.fields) { // tab past last column goes to next row
+		col = 0
+		rows++
+	} else if col < 0 {
+		col = len(v.fields) - 1
+		rows--
+	}
+	i += rows
+	if v.isDetailRow(i) { // skip over detail rows, which have no cells
+		if rows < 0 {
+			i--
+		} else {
+			i++
+		}
+	}
+	zint.Minimize(&i, count-1)
+	zint.Maximize(&i, 0)
+	if v.isDetailRow(i) { // the last row is a detail row
+		i--
+	}
+	v.moveCursor(v.getRowID(i), col)
+}
+
+func (v *TableView) handleCursorKey(key zui.KeyboardKey, mods zui.KeyboardModifier) bool {
+	if v.cursor.editing {
+		switch key {
+		case zui.KeyboardKeyReturn:
+			v.commitCellEdit()
+			return true
+		case zui.KeyboardKeyEscape:
+			v.cancelCellEdit()
+			return true
+		case zui.KeyboardKeyTab:
+			v.commitCellEdit()
+			if mods == zui.KeyboardModifierShift {
+				v.moveCursorBy(0, -1)
+			} else {
+				v.moveCursorBy(0, 1)
+			}
+			return true
+		}
+		return false
+	}
+	switch key {
+	case zui.KeyboardKeyUpArrow:
+		v.moveCursorBy(-1, 0)
+	case zui.KeyboardKeyDownArrow:
+		v.moveCursorBy(1, 0)
+	case zui.KeyboardKeyLeftArrow:
+		v.moveCursorBy(0, -1)
+	case zui.KeyboardKeyRightArrow:
+		v.moveCursorBy(0, 1)
+	case zui.KeyboardKeyTab:
+		if mods == zui.KeyboardModifierShift {
+			v.moveCursorBy(0, -1)
+		} else {
+			v.moveCursorBy(0, 1)
+		}
+	case zui.KeyboardKeyReturn:
+		v.startCellEdit(false)
+	default:
+		if mods == zui.KeyboardModifierNone && key >= ' ' && key < 127 {
+			v.startCellEdit(true)
+		}
+		return false // not handled, so a typed key goes into the cell we started editing
+	}
+	return true
+}
+
+// startCellEdit starts editing the text cell with the cursor, clearing it first if typed is set.
+// Checkboxes are toggled instead.
+func (v *TableView) startCellEdit(typed bool) {
+	fv, view := v.getCursorCellView(v.cursor.rowID, v.cursor.column)
+	if view == nil {
+		return
+	}
+	f := &v.fields[v.cursor.column]
+	if f.IsStatic() {
+		return
+	}
+	switch cv := view.(type) {
+	case *zui.TextView:
+		v.cursor.editing = true
+		v.cursor.editingText = cv.Text()
+		if typed {
+			cv.SetText("")
+		}
+		cv.Focus(true)
+	case *zui.CheckBox:
+		cv.SetValue(zbool.ToBoolInd(!cv.Value().Bool()))
+		v.commitCell(fv, f, view)
+	}
+}
+
+func (v *TableView) commitCell(fv *FieldView, f *Field, view zui.View) {
+	val, err := fv.fieldToDataItem(f, view, true)
+	if err != nil {
+		return
+	}
+	fv.callActionHandlerFunc(f, EditedAction, val.Interface(), &view)
+}
+
+// commitCellEdit stores the text of the cell being edited through the field, sending EditedAction.
+func (v *TableView) commitCellEdit() {
+	v.cursor.editing = false
+	fv, view := v.getCursorCellView(v.cursor.rowID, v.cursor.column)
+	if view == nil {
+		return
+	}
+	v.commitCell(fv, &v.fields[v.cursor.column], view)
+	zui.ViewGetNative(view).Focus(false)
+	v.Focus(true)
+}
+
+// cancelCellEdit sets the cell being edited back to the text it had before editing.
+func (v *TableView) cancelCellEdit() {
+	v.cursor.editing = false
+	fv, view := v.getCursorCellView(v.cursor.rowID, v.cursor.column)
+	tv, _ := view.(*zui.TextView)
+	if tv == nil {
+		return
+	}
+	tv.SetText(v.cursor.editingText)
+	fv.fieldToDataItem(&v.fields[v.cursor.column], tv, false) // the text view might have already set the data
+	tv.Focus(false)
+	v.Focus(true)
+}
@@ -0,0 +1,84 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"reflect"
+)
+
+// TableDetailBinding keeps a FieldView showing the struct of the row selected in a TableView.
+// Edits in the detail view are written to the row's struct, and the row updated.
+type TableDetailBinding struct {
+	Table  *TableView
+	Detail *FieldView
+	rowID  string
+}
+
+// BindTableToDetail makes detail show the row selected in table, following it when the table is sorted or updated.
+// If the row is removed, or none is selected, detail shows an empty struct and is disabled.
+// detail must be built for a struct of the table's row type.
+func BindTableToDetail(table *TableView, detail *FieldView) *TableDetailBinding {
+	b := &TableDetailBinding{Table: table, Detail: detail}
+	prevSelected := table.List.HandleRowSelected
+	table.List.HandleRowSelected = func(i int, selected, fromPressed bool) {
+		if prevSelected != nil {
+			prevSelected(i, selected, fromPressed)
+		}
+		if selected {
+			b.showRow(i)
+		} else if i == table.getRowIndexFromID(b.rowID) {
+			b.showRow(-1)
+		}
+	}
+	prevUpdated := table.RowsUpdated
+	table.RowsUpdated = func() {
+		if prevUpdated != nil {
+			prevUpdated()
+		}
+		b.rowsUpdated()
+	}
+	detail.addEditedHandler(func(f *Field) {
+		i := table.getRowIndexFromID(b.rowID)
+		if i != -1 {
+			edited := true
+			table.FlushDataToRow(i, edited)
+		}
+	})
+	b.showRow(table.List.SelectionIndex())
+	return b
+}
+
+// showRow shows the struct of row i in the detail view, or an empty, disabled one if i is -1.
+func (b *TableDetailBinding) showRow(i int) {
+	if i == -1 || i >= b.Table.GetRowCount() {
+		b.rowID = ""
+		sliceType := tableGetSliceRValFromPointer(b.Table.structure).Type()
+		b.Detail.SetStructure(reflect.New(sliceType.Elem()).Interface())
+		b.Detail.Update(false)
+		b.Detail.SetUsable(false)
+		return
+	}
+	b.rowID = b.Table.getRowID(i)
+	b.Detail.SetStructure(b.Table.GetRowData(i))
+	b.Detail.Update(false)
+	b.Detail.SetUsable(true)
+}
+
+// rowsUpdated finds the detail's row again, as the slice might be new, or the row moved or removed.
+func (b *TableDetailBinding) rowsUpdated() {
+	if b.rowID == "" {
+		return
+	}
+	i := b.Table.getRowIndexFromID(b.rowID)
+	if i == -1 {
+		b.showRow(-1)
+		return
+	}
+	if b.Table.List.SelectionIndex() != i {
+		b.Table.List.Select(i, true, false)
+	}
+	b.Detail.SetStructure(b.Table.GetRowData(i))
+	dontOverwriteEdited := true
+	b.Detail.Update(dontOverwriteEdited)
+}
@@ -0,0 +1,369 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/torlangballe/zui"
+	"github.com/torlangballe/zutil/zbool"
+	"github.com/torlangballe/zutil/zdict"
+	"github.com/torlangballe/zutil/zgeo"
+	"github.com/torlangballe/zutil/zlog"
+	"github.com/torlangballe/zutil/zreflect"
+	"github.com/torlangballe/zutil/zstr"
+)
+
+type ColumnFilterType string
+
+const (
+	ColumnFilterValues   ColumnFilterType = "values"   // rows with one of Values shown
+	ColumnFilterRange    ColumnFilterType = "range"    // rows with a number or time from Min to Max
+	ColumnFilterContains ColumnFilterType = "contains" // rows with Text anywhere, ignoring case
+	ColumnFilterPrefix   ColumnFilterType = "prefix"   // rows starting with Text, ignoring case
+	ColumnFilterRegex    ColumnFilterType = "regex"    // rows matching Text as a regular expression
+
+	tableFilterMark = " ⧩"
+)
+
+// ColumnFilter is a filter on the values of a TableView's column, set from its header's filter popup.
+type ColumnFilter struct {
+	Type   ColumnFilterType
+	Values []string `json:",omitempty"` // Values are texts of values as shown, for enum and bool columns
+	Min    string   `json:",omitempty"` // Min and Max are a range of numbers or times as typed. Either can be empty
+	Max    string   `json:",omitempty"`
+	Text   string   `json:",omitempty"`
+
+	regex *regexp.Regexp
+}
+
+func getColumnFilterType(f *Field) ColumnFilterType {
+	if f.Enum != "" || f.LocalEnum != "" || f.Kind == zreflect.KindBool {
+		return ColumnFilterValues
+	}
+	if f.Kind == zreflect.KindInt || f.Kind == zreflect.KindFloat || f.Kind == zreflect.KindTime {
+		return ColumnFilterRange
+	}
+	return ColumnFilterContains
+}
+
+// getFilterBound returns str parsed as a number or time for a field with an item like template.
+func getFilterBound(template zreflect.Item, f *Field, str string) (float64, bool) {
+	if str == "" {
+		return 0, false
+	}
+	if template.Kind == zreflect.KindTime {
+		t, err := parseTimeForField(str, f)
+		if err != nil {
+			return 0, false
+		}
+		return float64(t.UnixNano()) / float64(time.Second), true
+	}
+	item := makeTotalItem(template, 0)
+	if setNumberishItemFromString(item, f, str) != nil {
+		return 0, false
+	}
+	return getFloatFromTotalItem(item)
+}
+
+func (cf *ColumnFilter) passes(item zreflect.Item, f *Field, children []zreflect.Item) bool {
+	switch cf.Type {
+	case ColumnFilterValues:
+		return zstr.StringsContain(cf.Values, getItemDisplayString(item, f, children))
+	case ColumnFilterRange:
+		n, got := getFloatFromTotalItem(item)
+		if !got {
+			return false
+		}
+		min, got := getFilterBound(item, f, cf.Min)
+		if got && n < min {
+			return false
+		}
+		max, got := getFilterBound(item, f, cf.Max)
+		if got && n > max {
+			return false
+		}
+		return true
+	}
+	text := getItemDisplayString(item, f, children)
+	switch cf.Type {
+	case ColumnFilterPrefix:
+		return strings.HasPrefix(strings.ToLower(text), strings.ToLower(cf.Text))
+	case ColumnFilterRegex:
+		if cf.regex == nil {
+			var err error
+			cf.regex, err = regexp.Compile(cf.Text)
+			if err != nil {
+				return true
+			}
+		}
+		return cf.regex.MatchString(text)
+	}
+	return strings.Contains(strings.ToLower(text), strings.ToLower(cf.Text))
+}
+
+func (v *TableView) isFiltering() bool {
+	return v.filter != nil || len(v.columnFilters) != 0
+}
+
+// SetFilter sets a function that hides rows it returns false for, or clears it if filter is nil.
+func (v *TableView) SetFilter(filter func(rowData interface{}) bool) {
+	v.filter = filter
+	v.applyFilters()
+}
+
+func (v *TableView) findField(id string) *Field {
+	for i := range v.fields {
+		if v.fields[i].ID == id {
+			return &v.fields[i]
+		}
+	}
+	return nil
+}
+
+func (v *TableView) rowPassesFilters(rowData interface{}) bool {
+	if v.filter != nil && !v.filter(rowData) {
+		return false
+	}
+	if len(v.columnFilters) == 0 {
+		return true
+	}
+	defer lockStructure(rowData, false)()
+	root, err := zreflect.ItterateStruct(rowData, zreflect.Options{UnnestAnonymous: true})
+	if err != nil {
+		return true
+	}
+	for id, cf := range v.columnFilters {
+		f := v.findField(id)
+		if f != nil && !cf.passes(root.Children[f.Index], f, root.Children) {
+			return false
+		}
+	}
+	return true
+}
+
+// getShownRowIDs returns the ids of the rows currently shown.
+func (v *TableView) getShownRowIDs() zui.ListViewIDGetter {
+	if v.useRows {
+		return v.getRowIDs()
+	}
+	return getSliceIDGetter(tableGetSliceRValFromPointer(v.structure).Interface())
+}
+
+// applyFilters updates the rows shown after filters have changed.
+func (v *TableView) applyFilters() {
+	oldIDs := v.getShownRowIDs()
+	v.updateRows()
+	v.List.UpdateWithOldNewSlice(oldIDs, v.getShownRowIDs())
+	v.rowsUpdated()
+}
+
+// ColumnFilter returns the filter on the column of field fieldID, or nil if it has none.
+func (v *TableView) ColumnFilter(fieldID string) *ColumnFilter {
+	return v.columnFilters[fieldID]
+}
+
+// SetColumnFilter sets the filter on the column of field fieldID, or clears it if cf is nil.
+// The filters are stored, and used again next time a table with the same name is shown.
+func (v *TableView) SetColumnFilter(fieldID string, cf *ColumnFilter) {
+	if cf == nil {
+		delete(v.columnFilters, fieldID)
+	} else {
+		cf.regex = nil
+		v.columnFilters[fieldID] = cf
+	}
+	v.saveColumnFilters()
+	v.updateHeaderFilterMarks()
+	v.applyFilters()
+}
+
+// ClearColumnFilters removes all column filters.
+func (v *TableView) ClearColumnFilters() {
+	v.columnFilters = map[string]*ColumnFilter{}
+	v.saveColumnFilters()
+	v.updateHeaderFilterMarks()
+	v.applyFilters()
+}
+
+func (v *TableView) makeColumnFiltersKey() string {
+	return v.ObjectName() + ".columnFilters"
+}
+
+func (v *TableView) saveColumnFilters() {
+	data, err := json.Marshal(v.columnFilters)
+	if err != nil {
+		zlog.Error(err, "marshal")
+		return
+	}
+	zui.DefaultLocalKeyValueStore.SetString(string(data), v.makeColumnFiltersKey(), true)
+}
+
+func (v *TableView) loadColumnFilters() {
+	str, got := zui.DefaultLocalKeyValueStore.GetString(v.makeColumnFiltersKey())
+	if !got || str == "" {
+		return
+	}
+	filters := map[string]*ColumnFilter{}
+	err := json.Unmarshal([]byte(str), &filters)
+	if err != nil {
+		zlog.Error(err, "unmarshal", v.ObjectName())
+		return
+	}
+	for id := range filters {
+		if v.findField(id) == nil { // the field might have been removed since
+			delete(filters, id)
+		}
+	}
+	v.columnFilters = filters
+}
+
+// updateHeaderFilterMarks shows a mark after the title of columns with a filter.
+func (v *TableView) updateHeaderFilterMarks() {
+	if v.Header == nil {
+		return
+	}
+	for i, f := range v.fields {
+		if f.Flags&(flagHasHeaderImage|flagNoTitle) != 0 {
+			continue
+		}
+		view, _ := v.Header.FindViewWithName(f.ID, true)
+		to, _ := view.(zui.TextLayoutOwner)
+		if to == nil {
+			continue
+		}
+		title := getHeaderTitle(&v.fields[i])
+		if v.columnFilters[f.ID] != nil {
+			title += tableFilterMark
+		}
+		to.SetText(title)
+	}
+}
+
+// getDistinctColumnValues returns the texts of all the different values of f in the table's slice, filtered or not.
+func (v *TableView) getDistinctColumnValues(f *Field) []string {
+	set := map[string]bool{}
+	var add func(slice reflect.Value)
+	add = func(slice reflect.Value) {
+		for i := 0; i < slice.Len(); i++ {
+			rval := slice.Index(i)
+			unlock := lockRow(slice, i, false)
+			root, err := zreflect.ItterateStruct(rval.Addr().Interface(), zreflect.Options{UnnestAnonymous: true})
+			zlog.Assert(err == nil, err)
+			set[getItemDisplayString(root.Children[f.Index], f, root.Children)] = true
+			var children reflect.Value
+			if v.treeFieldName != "" {
+				children = rval.FieldByName(v.treeFieldName)
+			}
+			unlock() // before adding children, as they might share its lock
+			if children.IsValid() {
+				add(children)
+			}
+		}
+	}
+	add(tableGetSliceRValFromPointer(v.structure))
+	var values []string
+	for s := range set {
+		values = append(values, s)
+	}
+	sort.Strings(values)
+	return values
+}
+
+func addFilterRow(stack *zui.StackView, title string, view zui.View) {
+	row := zui.StackViewHor("")
+	label := zui.LabelNew(title)
+	label.SetMinWidth(60)
+	row.Add(label, zgeo.CenterLeft)
+	row.Add(view, zgeo.CenterLeft)
+	stack.Add(row, zgeo.TopLeft|zgeo.HorExpand)
+}
+
+// PresentColumnFilter shows a popup to set the filter of the column of field fieldID.
+// Enum and bool columns list their distinct values, numbers and times have a range, and text is matched.
+func (v *TableView) PresentColumnFilter(fieldID string) {
+	f := v.findField(fieldID)
+	if f == nil {
+		return
+	}
+	cf := ColumnFilter{Type: getColumnFilterType(f)}
+	old := v.columnFilters[fieldID]
+	if old != nil {
+		cf = *old
+	}
+	stack := zui.StackViewVert("filter")
+	stack.SetMargin(zgeo.RectFromXY2(10, 10, -10, -10))
+	var checks []*zui.CheckBox
+	var values []string
+	var minTV, maxTV, textTV *zui.TextView
+	var typeMenu *zui.MenuView
+	var style zui.TextViewStyle
+	switch cf.Type {
+	case ColumnFilterValues:
+		values = v.getDistinctColumnValues(f)
+		for _, val := range values {
+			on := (old == nil || zstr.StringsContain(cf.Values, val))
+			check := zui.CheckBoxNew(zbool.ToBoolInd(on))
+			checks = append(checks, check)
+			addFilterRow(stack, val, check)
+		}
+	case ColumnFilterRange:
+		minTV = zui.TextViewNew(cf.Min, style, 20, 1)
+		maxTV = zui.TextViewNew(cf.Max, style, 20, 1)
+		addFilterRow(stack, "from", minTV)
+		addFilterRow(stack, "to", maxTV)
+	default:
+		items := zdict.Items{
+			zdict.Item{"contains", ColumnFilterContains},
+			zdict.Item{"starts with", ColumnFilterPrefix},
+			zdict.Item{"regex", ColumnFilterRegex},
+		}
+		typeMenu = zui.MenuViewNew("type", items, cf.Type)
+		textTV = zui.TextViewNew(cf.Text, style, 20, 1)
+		addFilterRow(stack, "", typeMenu)
+		addFilterRow(stack, "text", textTV)
+	}
+	title := "Filter " + getHeaderTitle(f)
+	zui.PresentOKCanceledView(stack, title, zui.PresentViewAttributes{}, func(ok bool) bool {
+		if !ok {
+			return true
+		}
+		set := true
+		switch cf.Type {
+		case ColumnFilterValues:
+			cf.Values = nil
+			for i, check := range checks {
+				if check.Value().Bool() {
+					cf.Values = append(cf.Values, values[i])
+				}
+			}
+			set = (len(cf.Values) != len(values)) // all values on is no filter
+		case ColumnFilterRange:
+			cf.Min = strings.TrimSpace(minTV.Text())
+			cf.Max = strings.TrimSpace(maxTV.Text())
+			set = (cf.Min != "" || cf.Max != "")
+		default:
+			cf.Type = typeMenu.CurrentValue().(ColumnFilterType)
+			cf.Text = textTV.Text()
+			if cf.Type == ColumnFilterRegex {
+				_, err := regexp.Compile(cf.Text)
+				if err != nil {
+					zui.AlertShowError(err)
+					return false
+				}
+			}
+			set = (cf.Text != "")
+		}
+		if set {
+			v.SetColumnFilter(fieldID, &cf)
+		} else if old != nil {
+			v.SetColumnFilter(fieldID, nil)
+		}
+		return true
+	})
+}
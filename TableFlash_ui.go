@@ -0,0 +1,207 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"github.com/torlangballe/zui"
+	"github.com/torlangballe/zutil/zgeo"
+	"github.com/torlangballe/zutil/zreflect"
+	"github.com/torlangballe/zutil/ztimer"
+)
+
+// getRowTexts returns the text shown for each field in a row. Durations since a time are skipped, as they change all the time.
+func (v *TableView) getRowTexts(rowStruct interface{}) []string {
+	texts := make([]string, len(v.fields))
+	defer lockStructure(rowStruct, false)()
+	root, err := zreflect.ItterateStruct(rowStruct, zreflect.Options{UnnestAnonymous: true})
+	if err != nil {
+		return texts
+	}
+	for i, f := range v.fields {
+		if f.Flags&flagIsDuration != 0 {
+			continue
+		}
+		texts[i] = getItemDisplayString(root.Children[f.Index], &v.fields[i], root.Children)
+	}
+	return texts
+}
+
+// FlashRow highlights the row with id rowID, if visible, in col for FlashSecs.
+func (v *TableView) FlashRow(rowID string, col zgeo.Color) {
+	i := v.getRowIndexFromID(rowID)
+	if i == -1 {
+		return
+	}
+	fv, _ := v.List.GetVisibleRowViewFromIndex(i).(*FieldView)
+	if fv == nil {
+		return
+	}
+	fv.SetBGColor(col)
+	ztimer.StartIn(v.FlashSecs, func() {
+		i := v.getRowIndexFromID(rowID) // it might have moved
+		if i == -1 || v.List.GetVisibleRowViewFromIndex(i) != zui.View(fv) {
+			return
+		}
+		var rowCol zgeo.Color
+		if v.List.GetRowColor != nil {
+			rowCol = v.List.GetRowColor(i)
+		}
+		fv.SetBGColor(rowCol)
+	})
+}
+
+// FlashCell flashes the view of field with id fieldID in row rowID, if visible.
+func (v *TableView) FlashCell(rowID, fieldID string, col zgeo.Color) {
+	i := v.getRowIndexFromID(rowID)
+	if i == -1 {
+		return
+	}
+	fv, _ := v.List.GetVisibleRowViewFromIndex(i).(*FieldView)
+	if fv != nil {
+		v.flashCellView(fv, fieldID, col)
+	}
+}
+
+func (v *TableView) flashCellView(fv *FieldView, fieldID string, col zgeo.Color) {
+	view, _ := fv.findNamedViewOrInLabelized(fieldID)
+	if view == nil {
+		return
+	}
+	nv := zui.ViewGetNative(view)
+	nv.SetBGColor(col)
+	ztimer.StartIn(v.FlashSecs, func() {
+		nv.SetBGColor(zgeo.Color{})
+	})
+}
+
+func (v *TableView) flashChangedCells(fv *FieldView, oldTexts, texts []string) {
+	for j, f := range v.fields {
+		if j < len(oldTexts) && oldTexts[j] != texts[j] {
+			v.flashCellView(fv, f.ID, v.ChangedColor)
+		}
+	}
+}
+
+// flashChangedRow is called when row i's view fv has been updated, flashing any cells that changed.
+func (v *TableView) flashChangedRow(i int, fv *FieldView) {
+	if !v.FlashChanges || v.rowTexts == nil || v.isDetailRow(i) {
+		return
+	}
+	id := v.getRowID(i)
+	texts := v.getRowTexts(fv.structure)
+	oldTexts, got := v.rowTexts[id]
+	v.rowTexts[id] = texts
+	if got {
+		v.flashChangedCells(fv, oldTexts, texts)
+	}
+}
+
+// flashChangedRows stores the shown texts of all rows, flashing cells that changed in visible rows, and highlighting new ones.
+func (v *TableView) flashChangedRows() {
+	if !v.FlashChanges {
+		return
+	}
+	old := v.rowTexts
+	v.rowTexts = map[string][]string{}
+	first, last := v.List.GetFirstLastVisibleRowIndexes()
+	count := v.GetRowCount()
+	for i := 0; i < count; i++ {
+		if v.isDetailRow(i) {
+			continue
+		}
+		id := v.getRowID(i)
+		texts := v.getRowTexts(v.GetRowData(i))
+		v.rowTexts[id] = texts
+		if old == nil || i < first || i > last {
+			continue
+		}
+		oldTexts, got := old[id]
+		if !got {
+			v.FlashRow(id, v.InsertedColor)
+			continue
+		}
+		fv, _ := v.List.GetVisibleRowViewFromIndex(i).(*FieldView)
+		if fv != nil {
+			v.flashChangedCells(fv, oldTexts, texts)
+		}
+	}
+}
+
+// tableRemovedRow is a visible row removed by an update, faded out after it.
+type tableRemovedRow struct {
+	data interface{}
+	rect zgeo.Rect // rect is where the row was, relative to the table
+}
+
+// getRemovedRows returns the visible rows of oldGetter not in newGetter, before the list is updated.
+// oldData returns the data of old row i, or nil if it isn't faded out, like detail rows.
+func (v *TableView) getRemovedRows(oldGetter, newGetter zui.ListViewIDGetter, oldData func(i int) interface{}) []tableRemovedRow {
+	newIDs := map[string]bool{}
+	count := getIDGetterCount(newGetter)
+	for i := 0; i < count; i++ {
+		newIDs[newGetter.GetID(i)] = true
+	}
+	oldCount := getIDGetterCount(oldGetter)
+	var removed []tableRemovedRow
+	pos := zui.ViewGetNative(v).AbsoluteRect().Pos
+	first, last := v.List.GetFirstLastVisibleRowIndexes()
+	for i := first; i <= last && i < oldCount; i++ {
+		if i < 0 || newIDs[oldGetter.GetID(i)] {
+			continue
+		}
+		view := v.List.GetVisibleRowViewFromIndex(i)
+		data := oldData(i)
+		if view == nil || data == nil {
+			continue
+		}
+		rect := zui.ViewGetNative(view).AbsoluteRect()
+		rect.Pos = rect.Pos.Minus(pos)
+		removed = append(removed, tableRemovedRow{data: data, rect: rect})
+	}
+	return removed
+}
+
+// fadeOutRemovedRows shows a copy of each removed row where it was, on top of the already updated list, fading them out.
+func (v *TableView) fadeOutRemovedRows(removed []tableRemovedRow) {
+	if len(removed) == 0 {
+		return
+	}
+	nv := zui.ViewGetNative(v)
+	var ghosts []*zui.NativeView
+	for _, r := range removed {
+		ghost := v.createRowFromData(r.data, "") // no id, so it isn't recycled
+		gnv := zui.ViewGetNative(ghost)
+		nv.AddChild(ghost, -1)
+		gnv.SetRect(r.rect)
+		ghosts = append(ghosts, gnv)
+	}
+	const steps = 6
+	step := 0
+	timer := ztimer.RepeatNow(v.FlashSecs/2/steps, func() bool {
+		step++
+		if step > steps {
+			v.cancelFade()
+			return false
+		}
+		for _, g := range ghosts {
+			g.SetAlpha(float32(steps-step) / steps)
+		}
+		return true
+	})
+	v.stopFade = func() {
+		timer.Stop()
+		for _, g := range ghosts {
+			nv.RemoveChild(g)
+		}
+	}
+}
+
+// cancelFade removes rows being faded out, typically as a new update starts.
+func (v *TableView) cancelFade() {
+	if v.stopFade != nil {
+		stop := v.stopFade
+		v.stopFade = nil
+		stop()
+	}
+}
@@ -0,0 +1,171 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"math"
+	"reflect"
+	"strconv"
+	"time"
+
+	"github.com/torlangballe/zui"
+	"github.com/torlangballe/zutil/zbool"
+	"github.com/torlangballe/zutil/zreflect"
+)
+
+type columnTotal struct {
+	rows     int // rows is all rows added, for count totals of any kind of column
+	count    int // count is the rows with numbers, or non-zero times, to sum and compare
+	sum      float64
+	min      float64
+	max      float64
+	minItem  zreflect.Item
+	maxItem  zreflect.Item
+	template zreflect.Item
+}
+
+func makeFooterFields(fields []Field, height float64) []zui.Header {
+	headers := makeHeaderFields(fields, height)
+	for i := range headers {
+		headers[i].Title = ""
+		headers[i].Tip = ""
+		headers[i].ImagePath = ""
+		headers[i].SortSmallFirst = zbool.Unknown
+		headers[i].SortPriority = 0
+	}
+	return headers
+}
+
+// getFloatFromTotalItem returns a number to sum or compare with for ints, floats and non-zero times.
+func getFloatFromTotalItem(item zreflect.Item) (float64, bool) {
+	if item.Kind == zreflect.KindTime {
+		t, _ := item.Interface.(time.Time)
+		if t.IsZero() {
+			return 0, false
+		}
+		return float64(t.UnixNano()) / float64(time.Second), true
+	}
+	switch item.Value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(item.Value.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(item.Value.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return item.Value.Float(), true
+	}
+	return 0, false
+}
+
+// makeTotalItem makes a copy of template with value n, so it can be formatted as the field's column is.
+func makeTotalItem(template zreflect.Item, n float64) zreflect.Item {
+	item := template
+	rval := reflect.New(template.Value.Type()).Elem()
+	switch rval.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		rval.SetInt(int64(math.Round(n)))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		rval.SetUint(uint64(math.Round(n)))
+	case reflect.Float32, reflect.Float64:
+		rval.SetFloat(n)
+	}
+	item.Value = rval
+	item.Interface = rval.Interface()
+	item.Address = rval.Addr().Interface()
+	return item
+}
+
+// add adds item to the total. It is called with item's row locked, and keeps a copy of its value for min and max.
+func (ct *columnTotal) add(item zreflect.Item) {
+	ct.rows++
+	item.Value = reflect.ValueOf(item.Interface)
+	n, got := getFloatFromTotalItem(item)
+	if !got {
+		return
+	}
+	if ct.count == 0 || n < ct.min {
+		ct.min = n
+		ct.minItem = item
+	}
+	if ct.count == 0 || n > ct.max {
+		ct.max = n
+		ct.maxItem = item
+	}
+	ct.template = item
+	ct.sum += n
+	ct.count++
+}
+
+func (ct *columnTotal) text(f *Field) string {
+	if f.Total == "count" {
+		return strconv.Itoa(ct.rows)
+	}
+	if ct.count == 0 {
+		return ""
+	}
+	switch f.Total {
+	case "min":
+		return getTextFromNumberishItem(ct.minItem, f)
+	case "max":
+		return getTextFromNumberishItem(ct.maxItem, f)
+	}
+	if f.Kind == zreflect.KindTime { // sum and average of times are meaningless
+		return ""
+	}
+	n := ct.sum
+	if f.Total == "avg" {
+		n /= float64(ct.count)
+	}
+	return getTextFromNumberishItem(makeTotalItem(ct.template, n), f)
+}
+
+// updateFooter calculates the totals of columns with a total tag, using the rows of the slice that pass the filters.
+// Children in a tree are included whether they are shown or not, and parents shown only as they have children passing aren't.
+func (v *TableView) updateFooter() {
+	if v.Footer == nil {
+		return
+	}
+	totals := map[int]*columnTotal{}
+	for _, f := range v.fields {
+		if f.Total != "" {
+			totals[f.Index] = &columnTotal{}
+		}
+	}
+	var add func(slice reflect.Value)
+	add = func(slice reflect.Value) {
+		for i := 0; i < slice.Len(); i++ {
+			rval := slice.Index(i)
+			data := rval.Addr().Interface()
+			passes := !v.isFiltering() || v.rowPassesFilters(data)
+			unlock := lockRow(slice, i, false)
+			if passes {
+				root, err := zreflect.ItterateStruct(data, zreflect.Options{UnnestAnonymous: true})
+				if err == nil {
+					for index, ct := range totals {
+						ct.add(root.Children[index])
+					}
+				}
+			}
+			var children reflect.Value
+			if v.treeFieldName != "" {
+				children = rval.FieldByName(v.treeFieldName)
+			}
+			unlock() // before adding children, as they might share its lock
+			if children.IsValid() {
+				add(children)
+			}
+		}
+	}
+	add(tableGetSliceRValFromPointer(v.structure))
+	for i, f := range v.fields {
+		ct := totals[f.Index]
+		if ct == nil {
+			continue
+		}
+		view, _ := v.Footer.FindViewWithName(f.ID, true)
+		to, _ := view.(zui.TextLayoutOwner)
+		if to != nil {
+			to.SetText(ct.text(&v.fields[i]))
+		}
+	}
+}
@@ -0,0 +1,79 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"reflect"
+
+	"github.com/torlangballe/zui"
+)
+
+// CanMoveRows returns true if Ordered is set, and the rows aren't sorted by the header or a tree.
+func (v *TableView) CanMoveRows() bool {
+	return v.Ordered && v.treeFieldName == "" && (v.Header == nil || len(v.Header.SortOrder) == 0)
+}
+
+// MoveRow moves the row with rowID up (delta negative) or down delta rows of those shown, re-ordering the table's slice.
+// It returns false if it can't be moved. RowMoved is called after it is.
+func (v *TableView) MoveRow(rowID string, delta int) bool {
+	if !v.CanMoveRows() || delta == 0 {
+		return false
+	}
+	i := v.getRowIndexFromID(rowID)
+	if i == -1 {
+		return false
+	}
+	dir := 1
+	if delta < 0 {
+		dir = -1
+	}
+	t := i
+	for n := 0; n != delta; n += dir {
+		t += dir
+		for v.isDetailRow(t) {
+			t += dir
+		}
+	}
+	if t < 0 || t >= v.GetRowCount() {
+		return false
+	}
+	val := tableGetSliceRValFromPointer(v.structure)
+	oldSlice := val.Interface()
+	indexes := getSliceIDIndexes(getSliceIDGetter(oldSlice), val.Len())
+	from := indexes[rowID]
+	to := indexes[v.getRowID(t)] // with a filter, the row shown next to it might not be next in the slice
+	nval := reflect.MakeSlice(val.Type(), val.Len(), val.Len())
+	copyRowsLocked(nval, val)
+	moveInSlice(nval, from, to)
+	val.Set(nval)
+	v.UpdateWithOldNewSlice(oldSlice, nval.Interface())
+	i = v.getRowIndexFromID(rowID)
+	if i != -1 {
+		v.List.Select(i, true, false)
+	}
+	if v.RowMoved != nil {
+		v.RowMoved(rowID)
+	}
+	return true
+}
+
+// handleOrderKey moves the selected row with alt-up and alt-down arrows.
+func (v *TableView) handleOrderKey(key zui.KeyboardKey, mods zui.KeyboardModifier) bool {
+	if !v.Ordered || mods != zui.KeyboardModifierAlt || v.cursor.editing {
+		return false
+	}
+	i := v.List.SelectionIndex()
+	if i == -1 {
+		return false
+	}
+	switch key {
+	case zui.KeyboardKeyUpArrow:
+		v.MoveRow(v.getRowID(i), -1)
+	case zui.KeyboardKeyDownArrow:
+		v.MoveRow(v.getRowID(i), 1)
+	default:
+		return false
+	}
+	return true
+}
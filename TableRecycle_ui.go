@@ -0,0 +1,67 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"github.com/torlangballe/zui"
+	"github.com/torlangballe/zutil/zgeo"
+)
+
+// addRowToRecycle makes fv be put in the recycled row views when it is removed from the list, typically when scrolled out.
+func (v *TableView) addRowToRecycle(fv *FieldView) {
+	fv.AddOnRemoveFunc(func() {
+		v.recycleRow(fv)
+	})
+}
+
+// recycleRow puts fv in the recycled row views, unless it already is, or was made with columns no longer shown.
+func (v *TableView) recycleRow(fv *FieldView) {
+	if v.recycledRows[fv] || !v.hasRowFields(fv) {
+		return
+	}
+	v.recycledRows[fv] = true
+	v.rowPool = append(v.rowPool, fv)
+}
+
+// hasRowFields returns true if fv was made with the table's current fields, which are new if its columns have changed.
+func (v *TableView) hasRowFields(fv *FieldView) bool {
+	return len(fv.fields) == len(v.fields) && (len(v.fields) == 0 || &fv.fields[0] == &v.fields[0])
+}
+
+// getRecycledRow returns a row view no longer in the list, bound to data for row i with rowID, or nil if there are none.
+func (v *TableView) getRecycledRow(data interface{}, rowID string, i int) *FieldView {
+	if len(v.rowPool) == 0 {
+		return nil
+	}
+	fv := v.rowPool[len(v.rowPool)-1]
+	v.rowPool = v.rowPool[:len(v.rowPool)-1]
+	delete(v.recycledRows, fv)
+	fv.id = rowID
+	fv.SetObjectName(rowID)
+	fv.SetStructure(data)
+	fv.ClearEdited()                  // it was edited as another row
+	zui.ViewGetNative(fv).SetAlpha(1) // it might have faded out when removed
+	var col zgeo.Color
+	if v.List.GetRowColor != nil {
+		col = v.List.GetRowColor(i)
+	}
+	fv.SetBGColor(col) // it might have been flashing
+	if v.cursor.rowID != "" && v.cursor.column < len(v.fields) {
+		view, _ := fv.findNamedViewOrInLabelized(v.fields[v.cursor.column].ID)
+		showCursorInView(view, false)
+	}
+	v.bindTreeDisclosure(fv, rowID)
+	fv.registerTimeLabels() // they were unregistered when it was removed
+	fv.timesUp = nil
+	v.showCursorInNewRow(fv, rowID)
+	dontOverwriteEdited := false
+	fv.Update(dontOverwriteEdited)
+	return fv
+}
+
+// clearRecycledRows removes recycled row views, when they can't be re-used, as columns have changed.
+func (v *TableView) clearRecycledRows() {
+	v.rowPool = nil
+	v.recycledRows = map[*FieldView]bool{}
+}
@@ -0,0 +1,65 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/torlangballe/zutil/zgeo"
+)
+
+type benchRow struct {
+	ID      string    `zui:"id"`
+	Name    string    `zui:"static"`
+	Amount  float64   `zui:"static,fracts:2"`
+	Count   int       `zui:"static"`
+	Enabled bool      `zui:"static"`
+	Changed time.Time `zui:"static"`
+}
+
+const (
+	benchRowCount   = 100000
+	benchRowsOnView = 40 // benchRowsOnView is how many rows are shown at once while scrolling
+)
+
+func makeBenchRows() []benchRow {
+	rows := make([]benchRow, benchRowCount)
+	now := time.Now()
+	for i := range rows {
+		rows[i] = benchRow{ID: fmt.Sprint(i), Name: fmt.Sprint("row ", i), Amount: float64(i) / 3, Count: i, Enabled: i%2 == 0, Changed: now}
+	}
+	return rows
+}
+
+// benchmarkScrollRows scrolls a table through all its rows, creating each as it scrolls in.
+// Rows scrolling out are recycled as the remove handler addRowToRecycle adds does if pooled, or dropped if not.
+func benchmarkScrollRows(b *testing.B, pooled bool) {
+	rows := makeBenchRows()
+	v := TableViewNew("bench", true, &rows)
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		var shown []*FieldView
+		for i := range rows {
+			fv := v.createRow(zgeo.Size{}, v.getRowID(i), i).(*FieldView)
+			shown = append(shown, fv)
+			if len(shown) > benchRowsOnView {
+				if pooled {
+					v.recycleRow(shown[0])
+				}
+				shown = shown[1:]
+			}
+		}
+		v.clearRecycledRows()
+	}
+}
+
+func BenchmarkScrollRowsPooled(b *testing.B) {
+	benchmarkScrollRows(b, true)
+}
+
+func BenchmarkScrollRowsUnpooled(b *testing.B) {
+	benchmarkScrollRows(b, false)
+}
@@ -0,0 +1,94 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"math"
+	"strings"
+
+	"github.com/torlangballe/zui"
+	"github.com/torlangballe/zutil/zgeo"
+)
+
+const tableDetailRowIDSuffix = ".detail"
+
+var TableDefaultDetailLabelizeWidth = 160.0
+
+func (v *TableView) isDetailRow(i int) bool {
+	return v.useRows && i >= 0 && i < len(v.rows) && v.rows[i].isDetail
+}
+
+func getDetailToggleText(expanded bool) string {
+	if expanded {
+		return "⊖"
+	}
+	return "⊕"
+}
+
+// createDetailRow creates a vertical FieldView with all fields of data, for the detail row with id detailID.
+// Edits in it update the row it is the detail of.
+func (v *TableView) createDetailRow(data interface{}, detailID string) zui.View {
+	params := FieldViewParametersDefault()
+	params.LabelizeWidth = TableDefaultDetailLabelizeWidth
+	fv := FieldViewNew(detailID, data, params)
+	left := v.RowInset
+	if v.hasDisclosureColumn() {
+		left += v.TreeWidth
+	}
+	fv.SetMargin(zgeo.RectFromXY2(left, 6, -math.Max(16, v.RowInset), -6))
+	rowID := strings.TrimSuffix(detailID, tableDetailRowIDSuffix)
+	fv.addEditedHandler(func(f *Field) {
+		i := v.getRowIndexFromID(rowID)
+		if i != -1 {
+			edited := true
+			v.FlushDataToRow(i, edited)
+		}
+	})
+	update := true
+	showStatic := true
+	fv.Build(update, showStatic)
+	return fv
+}
+
+// getDetailRowHeight returns the height of detail row i, measuring a view for it the first time.
+func (v *TableView) getDetailRowHeight(i int) float64 {
+	row := &v.rows[i]
+	h, got := v.rowHeights[row.id]
+	if got {
+		return h
+	}
+	view := v.List.GetVisibleRowViewFromIndex(i)
+	if view != nil {
+		h = v.measureRowView(view)
+	} else {
+		fv := v.createDetailRow(row.data, row.id).(*FieldView)
+		h = v.measureRowView(fv)
+		unregisterChangeListener(fv) // it is never shown, so never removed
+		fv.unregisterTimeLabels()
+	}
+	v.rowHeights[row.id] = h
+	return h
+}
+
+func (v *TableView) IsRowDetailExpanded(rowID string) bool {
+	return v.detailsExpanded[rowID]
+}
+
+// SetRowDetailExpanded shows or hides a row below the row with rowID, showing all its fields.
+// RowDetails must be set. Expanded rows stay expanded when the table is updated with UpdateWithOldNewSlice.
+func (v *TableView) SetRowDetailExpanded(rowID string, expanded bool) {
+	if v.detailsExpanded[rowID] == expanded {
+		return
+	}
+	if expanded {
+		v.detailsExpanded[rowID] = true
+	} else {
+		delete(v.detailsExpanded, rowID)
+		delete(v.rowHeights, rowID+tableDetailRowIDSuffix)
+	}
+	if !v.RowDetails {
+		return
+	}
+	v.updateRowsAround(rowID)
+}
@@ -0,0 +1,165 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"math"
+	"strings"
+
+	"github.com/torlangballe/zui"
+	"github.com/torlangballe/zutil/zgeo"
+	"github.com/torlangballe/zutil/ztimer"
+)
+
+const (
+	tableLineHeightFactor = 1.25 // tableLineHeightFactor is a line's height relative to its font size
+	tableCharWidthFactor  = 0.55 // tableCharWidthFactor is the average width of a character relative to its font size
+	tableRowPadding       = 8
+)
+
+// getMeasuredRowHeight returns the height of row i when MeasureRowHeights is set, measuring it if its view is visible,
+// or estimating it from its fields otherwise, as the list asks for the height of every row.
+func (v *TableView) getMeasuredRowHeight(i int) float64 {
+	id := v.getRowID(i)
+	h, got := v.rowHeights[id]
+	if got {
+		return h
+	}
+	h = v.DefaultHeight
+	view := v.List.GetVisibleRowViewFromIndex(i)
+	if view != nil {
+		h = math.Max(h, v.measureRowView(view))
+	} else {
+		data := v.GetRowData(i)
+		if data != nil {
+			texts := v.getRowTexts(data)
+			for j := range v.fields {
+				h = math.Max(h, estimateFieldHeight(&v.fields[j], texts[j]))
+			}
+		}
+	}
+	v.rowHeights[id] = h
+	return h
+}
+
+// estimateFieldHeight returns how high f's view showing text needs to be.
+// Multi-line fields are Rows lines high if editable, or as many lines as text wraps to, up to Rows, if static.
+func estimateFieldHeight(f *Field, text string) float64 {
+	var h float64
+	if f.Rows > 1 {
+		fontSize := f.FontSize
+		if fontSize == 0 {
+			fontSize = zgeo.FontDefaultSize
+		}
+		lines := f.Rows
+		if f.IsStatic() {
+			lines = strings.Count(text, "\n") + 1
+			width := f.MaxWidth
+			if width == 0 {
+				width = f.Size.W
+			}
+			if width != 0 {
+				perLine := int(width / (fontSize * tableCharWidthFactor))
+				if perLine > 0 {
+					wrapped := (len([]rune(text)) + perLine - 1) / perLine
+					if wrapped > lines {
+						lines = wrapped
+					}
+				}
+			}
+			if lines > f.Rows {
+				lines = f.Rows
+			}
+		}
+		h = float64(lines)*fontSize*tableLineHeightFactor + tableRowPadding
+	}
+	h = math.Max(h, f.Height)
+	if f.Size.H != 0 {
+		h = math.Max(h, f.Size.H+tableRowPadding)
+	}
+	return h
+}
+
+// measureRowView returns the height view needs to lay out its content at the list's width.
+func (v *TableView) measureRowView(view zui.View) float64 {
+	w := v.List.LocalRect().Size.W
+	return view.CalculatedSize(zgeo.Size{w, 0}).H
+}
+
+// updateRowHeight measures or estimates row i again after it is flushed, queuing a reload of the list if its height changed.
+func (v *TableView) updateRowHeight(i int) {
+	if !v.MeasureRowHeights && !v.isDetailRow(i) {
+		return
+	}
+	id := v.getRowID(i)
+	old, got := v.rowHeights[id]
+	if !got {
+		return
+	}
+	view := v.List.GetVisibleRowViewFromIndex(i)
+	if view == nil { // a row changed while not shown gets a new estimate
+		delete(v.rowHeights, id)
+		if v.GetRowHeight(i) != old {
+			v.queueReload()
+		}
+		return
+	}
+	h := v.measureRowView(view)
+	if !v.isDetailRow(i) {
+		h = math.Max(h, v.DefaultHeight)
+	}
+	if h != old {
+		v.rowHeights[id] = h
+		v.queueReload()
+	}
+}
+
+// queueReload reloads the list once after the current updates, however many rows change height in them.
+func (v *TableView) queueReload() {
+	if v.reloadQueued {
+		return
+	}
+	v.reloadQueued = true
+	ztimer.StartIn(0, func() {
+		v.reloadQueued = false
+		v.List.ReloadData()
+	})
+}
+
+// clearMeasuredRowHeights removes the measured heights of all rows, so they are measured again, as when columns change.
+// Detail rows' heights are kept, as their columns don't change.
+func (v *TableView) clearMeasuredRowHeights() {
+	if !v.MeasureRowHeights {
+		return
+	}
+	for id := range v.rowHeights {
+		if !strings.HasSuffix(id, tableDetailRowIDSuffix) {
+			delete(v.rowHeights, id)
+		}
+	}
+}
+
+// pruneRowHeights removes the heights of rows before the slice is updated, except rows visible in the list that are still in newIDs.
+// Their views are updated, measuring them again, while other rows might have changed, so are estimated or measured again when shown.
+func (v *TableView) pruneRowHeights(oldIDs, newIDs zui.ListViewIDGetter) {
+	visible := map[string]bool{}
+	first, last := v.List.GetFirstLastVisibleRowIndexes()
+	oldCount := getIDGetterCount(oldIDs)
+	for i := first; i <= last && i >= 0 && i < oldCount; i++ {
+		visible[oldIDs.GetID(i)] = true
+	}
+	keep := map[string]bool{}
+	count := getIDGetterCount(newIDs)
+	for i := 0; i < count; i++ {
+		id := newIDs.GetID(i)
+		if visible[id] {
+			keep[id] = true
+		}
+	}
+	for id := range v.rowHeights {
+		if !keep[id] {
+			delete(v.rowHeights, id)
+		}
+	}
+}
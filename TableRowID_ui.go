@@ -0,0 +1,137 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"strconv"
+	"sync"
+
+	"github.com/torlangballe/zui"
+	"github.com/torlangballe/zutil/zlog"
+	"github.com/torlangballe/zutil/zreflect"
+)
+
+// rowKeys is the fields used to make ids for rows of a struct type without a GetID method.
+// If isID is set, names is a single field with an id tag, used as-is, otherwise names are fields with key tags, hashed.
+// If names is empty, rows' indexes are their ids.
+type rowKeys struct {
+	names []string
+	isID  bool
+}
+
+var (
+	rowKeysCache     = map[reflect.Type]rowKeys{}
+	rowKeysCacheLock sync.Mutex
+)
+
+func getRowKeys(structType reflect.Type) rowKeys {
+	rowKeysCacheLock.Lock()
+	defer rowKeysCacheLock.Unlock()
+	keys, got := rowKeysCache[structType]
+	if got {
+		return keys
+	}
+	root, err := zreflect.ItterateStruct(reflect.New(structType).Interface(), zreflect.Options{UnnestAnonymous: true})
+	zlog.Assert(err == nil, err)
+	for _, item := range root.Children {
+		for _, part := range zreflect.GetTagAsMap(item.Tag)["zui"] {
+			switch part {
+			case "id":
+				keys = rowKeys{names: []string{item.FieldName}, isID: true}
+			case "key":
+				if !keys.isID {
+					keys.names = append(keys.names, item.FieldName)
+				}
+			}
+		}
+		if keys.isID {
+			break
+		}
+	}
+	if len(keys.names) == 0 {
+		zlog.Error(nil, "slice isn't a zui.ListViewIDGetter, and its struct has no fields with id or key tags, using row indexes as ids:", structType)
+	}
+	rowKeysCache[structType] = keys
+	return keys
+}
+
+// makeRowIDFromKeys returns the value of an id field as a string, or a hash of the values of key fields, or index i if there are none.
+func makeRowIDFromKeys(rval reflect.Value, i int, keys rowKeys) string {
+	if len(keys.names) == 0 {
+		return strconv.Itoa(i)
+	}
+	if rval.CanAddr() {
+		defer lockStructure(rval.Addr().Interface(), false)()
+	}
+	if keys.isID {
+		return fmt.Sprint(rval.FieldByName(keys.names[0]).Interface())
+	}
+	h := fnv.New64a()
+	for _, name := range keys.names {
+		fmt.Fprint(h, rval.FieldByName(name).Interface(), "\x1f")
+	}
+	return strconv.FormatUint(h.Sum64(), 36)
+}
+
+// getSliceRowID returns the id of row i in slice, as getSliceIDGetter does, so duplicates get the same suffix.
+// Tables only use it for slices that are a zui.ListViewIDGetter; other slices have their ids in the table's rows.
+func getSliceRowID(slice reflect.Value, i int) string {
+	return getSliceIDGetter(slice.Interface()).GetID(i)
+}
+
+// hasRowKeys returns true if the rows of slice have ids of their own, not their indexes,
+// so rows from elsewhere, like pasted or imported ones, can be matched with them.
+func hasRowKeys(slice reflect.Value) bool {
+	_, got := slice.Interface().(zui.ListViewIDGetter)
+	return got || len(getRowKeys(slice.Type().Elem()).names) != 0
+}
+
+// getTreeRowIDs returns the ids of the rows of slice, which are children of the row with parentID in a tree, or top rows if it is "".
+// Children's ids start with their parent's, so they are unique in the tree, even when they are their indexes.
+func getTreeRowIDs(slice reflect.Value, parentID string) zui.ListViewIDGetter {
+	getter := getSliceIDGetter(slice.Interface())
+	if parentID == "" {
+		return getter
+	}
+	ids := make(tableRowIDs, slice.Len())
+	for i := range ids {
+		ids[i] = parentID + "/" + getter.GetID(i)
+	}
+	return ids
+}
+
+// getSliceIDGetter returns slice if it is a zui.ListViewIDGetter, or otherwise the ids of its rows made from its id or key tags.
+// Rows with the same id, from equal keys or a hash collision, are logged, and get their index added to the id, so the list can tell them apart.
+func getSliceIDGetter(slice interface{}) zui.ListViewIDGetter {
+	getter, _ := slice.(zui.ListViewIDGetter)
+	if getter != nil {
+		return getter
+	}
+	val := reflect.ValueOf(slice)
+	keys := getRowKeys(val.Type().Elem())
+	ids := make(tableRowIDs, val.Len())
+	seen := make(map[string]bool, len(ids))
+	for i := range ids {
+		id := makeRowIDFromKeys(val.Index(i), i, keys)
+		if seen[id] {
+			zlog.Error(nil, "duplicate row id in slice of", val.Type().Elem(), "at index", i, ":", id, keys.names)
+			id += "#" + strconv.Itoa(i)
+		}
+		seen[id] = true
+		ids[i] = id
+	}
+	return ids
+}
+
+// getIDGetterCount returns the number of ids in getter, which is a slice or has a Count method.
+func getIDGetterCount(getter zui.ListViewIDGetter) int {
+	counter, _ := getter.(interface{ Count() int })
+	if counter != nil {
+		return counter.Count()
+	}
+	return reflect.ValueOf(getter).Len()
+}
@@ -0,0 +1,259 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"encoding/json"
+	"io"
+
+	"github.com/torlangballe/zui"
+	"github.com/torlangballe/zutil/zdict"
+	"github.com/torlangballe/zutil/zlog"
+)
+
+const (
+	tableStateMenuSave   = "$save"
+	tableStateMenuDelete = "$delete"
+)
+
+// TableColumnState is a column shown in a TableViewState.
+type TableColumnState struct {
+	ID    string
+	Width float64 `json:",omitempty"` // Width is zero for the column's own width
+}
+
+// TableViewState is a named view of a TableView: the columns shown, in order and with widths, its sorting and filters.
+// States are stored as JSON, so they can be exported and shared in config files.
+type TableViewState struct {
+	Name      string
+	Columns   []TableColumnState
+	SortOrder []zui.SortInfo           `json:",omitempty"`
+	Filters   map[string]*ColumnFilter `json:",omitempty"`
+}
+
+// CurrentState returns the table's current columns, sorting and filters as a state called name.
+func (v *TableView) CurrentState(name string) TableViewState {
+	state := TableViewState{Name: name}
+	for _, f := range v.fields {
+		state.Columns = append(state.Columns, TableColumnState{ID: f.ID, Width: v.columnWidths[f.ID]})
+	}
+	if v.Header != nil {
+		state.SortOrder = append(state.SortOrder, v.Header.SortOrder...)
+	}
+	if len(v.columnFilters) != 0 {
+		state.Filters = map[string]*ColumnFilter{}
+		for id, cf := range v.columnFilters {
+			c := *cf
+			state.Filters[id] = &c
+		}
+	}
+	return state
+}
+
+// SetColumnWidth makes the column of field fieldID width wide, or its own width if width is zero.
+func (v *TableView) SetColumnWidth(fieldID string, width float64) {
+	state := v.CurrentState("")
+	for i, c := range state.Columns {
+		if c.ID == fieldID {
+			state.Columns[i].Width = width
+		}
+	}
+	v.ApplyState(state)
+}
+
+// ApplyState shows the columns of state, sorting and filtering with it.
+// Columns of fields that don't exist are skipped, and if state has no columns, all are shown.
+func (v *TableView) ApplyState(state TableViewState) {
+	var fields []Field // a new slice, as rows' views share the old one
+	v.columnWidths = map[string]float64{}
+	for _, c := range state.Columns {
+		for _, f := range v.allFields {
+			if f.ID != c.ID {
+				continue
+			}
+			if c.Width != 0 {
+				f.MinWidth = c.Width
+				f.MaxWidth = c.Width
+				v.columnWidths[f.ID] = c.Width
+			}
+			fields = append(fields, f)
+			break
+		}
+	}
+	v.fieldsOrdered = (len(fields) != 0)
+	if len(fields) == 0 {
+		fields = append(fields, v.allFields...)
+	}
+	v.fields = fields
+	if v.cursor.column >= len(v.fields) {
+		v.cursor.column = 0
+	}
+	v.columnFilters = map[string]*ColumnFilter{}
+	for id, cf := range state.Filters {
+		if v.findField(id) != nil {
+			c := *cf
+			v.columnFilters[id] = &c
+		}
+	}
+	v.saveColumnFilters()
+	if v.Header != nil {
+		v.Header.SortOrder = append([]zui.SortInfo{}, state.SortOrder...)
+		v.Header.RemoveAllChildren()
+		v.Header.Populate(v.addTreeHeader(makeHeaderFields(v.fields, v.HeaderHeight)))
+		v.updateHeaderFilterMarks()
+		v.sortSlice(tableGetSliceRValFromPointer(v.structure).Interface())
+	}
+	if v.Footer != nil {
+		v.Footer.RemoveAllChildren()
+		v.Footer.Populate(v.addTreeHeader(makeFooterFields(v.fields, v.HeaderHeight)))
+	}
+	v.clearMeasuredRowHeights()
+	v.clearRecycledRows()
+	v.Reload()
+	v.rowsUpdated()
+	v.ArrangeChildren()
+}
+
+func (v *TableView) makeStatesKey() string {
+	return v.ObjectName() + ".states"
+}
+
+// SavedStates returns the named states stored for tables with the same name as this one.
+func (v *TableView) SavedStates() []TableViewState {
+	var states []TableViewState
+	str, got := zui.DefaultLocalKeyValueStore.GetString(v.makeStatesKey())
+	if !got || str == "" {
+		return nil
+	}
+	err := json.Unmarshal([]byte(str), &states)
+	if err != nil {
+		zlog.Error(err, "unmarshal", v.ObjectName())
+		return nil
+	}
+	return states
+}
+
+func (v *TableView) storeStates(states []TableViewState) {
+	data, err := json.Marshal(states)
+	if err != nil {
+		zlog.Error(err, "marshal")
+		return
+	}
+	zui.DefaultLocalKeyValueStore.SetString(string(data), v.makeStatesKey(), true)
+}
+
+func findTableState(states []TableViewState, name string) int {
+	for i, s := range states {
+		if s.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// SaveState stores the table's current state with name, replacing any with the same name.
+func (v *TableView) SaveState(name string) {
+	v.addStates([]TableViewState{v.CurrentState(name)})
+	v.stateName = name
+}
+
+func (v *TableView) addStates(add []TableViewState) {
+	states := v.SavedStates()
+	for _, s := range add {
+		i := findTableState(states, s.Name)
+		if i == -1 {
+			states = append(states, s)
+		} else {
+			states[i] = s
+		}
+	}
+	v.storeStates(states)
+}
+
+// DeleteState removes the stored state with name.
+func (v *TableView) DeleteState(name string) {
+	states := v.SavedStates()
+	i := findTableState(states, name)
+	if i != -1 {
+		states = append(states[:i], states[i+1:]...)
+		v.storeStates(states)
+	}
+	if v.stateName == name {
+		v.stateName = ""
+	}
+}
+
+// ApplySavedState applies the stored state with name, returning false if there is none.
+func (v *TableView) ApplySavedState(name string) bool {
+	states := v.SavedStates()
+	i := findTableState(states, name)
+	if i == -1 {
+		return false
+	}
+	v.ApplyState(states[i])
+	v.stateName = name
+	return true
+}
+
+// ExportStates writes the stored states as JSON, to be imported with ImportStates.
+func (v *TableView) ExportStates(w io.Writer) error {
+	e := json.NewEncoder(w)
+	e.SetIndent("", "  ")
+	return e.Encode(v.SavedStates())
+}
+
+// ImportStates reads states written with ExportStates, storing them, and replacing any with the same names.
+func (v *TableView) ImportStates(r io.Reader) error {
+	var states []TableViewState
+	err := json.NewDecoder(r).Decode(&states)
+	if err != nil {
+		return err
+	}
+	v.addStates(states)
+	return nil
+}
+
+func (v *TableView) makeStatesMenuItems() zdict.Items {
+	var items zdict.Items
+	for _, s := range v.SavedStates() {
+		items = append(items, zdict.Item{s.Name, s.Name})
+	}
+	items = append(items, zdict.Item{"Save View…", tableStateMenuSave})
+	if v.stateName != "" {
+		items = append(items, zdict.Item{"Delete " + v.stateName, tableStateMenuDelete})
+	}
+	return items
+}
+
+// MakeStatesMenu creates a menu to switch between the table's stored states, and save or delete them.
+func (v *TableView) MakeStatesMenu() *zui.MenuView {
+	menu := zui.MenuViewNew(v.ObjectName()+".states", v.makeStatesMenuItems(), v.stateName)
+	menu.SetSelectedHandler(func() {
+		switch val := menu.CurrentValue().(string); val {
+		case tableStateMenuSave:
+			v.presentSaveState(menu)
+			return
+		case tableStateMenuDelete:
+			v.DeleteState(v.stateName)
+		default:
+			v.ApplySavedState(val)
+		}
+		menu.UpdateItems(v.makeStatesMenuItems(), []interface{}{v.stateName})
+	})
+	return menu
+}
+
+func (v *TableView) presentSaveState(menu *zui.MenuView) {
+	s := struct {
+		Name string
+	}{Name: v.stateName}
+	params := FieldViewParametersDefault()
+	PresentOKCancelStruct(&s, params, "Save View", zui.PresentViewAttributes{}, func(ok bool) bool {
+		if ok && s.Name != "" {
+			v.SaveState(s.Name)
+		}
+		menu.UpdateItems(v.makeStatesMenuItems(), []interface{}{v.stateName})
+		return true
+	})
+}
@@ -0,0 +1,256 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"reflect"
+
+	"github.com/torlangballe/zui"
+	"github.com/torlangballe/zutil/zgeo"
+)
+
+const tableTreeColumnID = "$tree"
+
+// tableRow is a row shown in a TableView, when it isn't simply the slice's elements, as in a tree or with detail rows.
+type tableRow struct {
+	data        interface{} // data is a pointer to the row's struct
+	id          string
+	depth       int
+	hasChildren bool
+	isDetail    bool // isDetail is set for a row showing all fields of the row before it
+}
+
+// tableRowIDs is the ids of rows shown, used to update the list with old and new rows.
+type tableRowIDs []string
+
+func (ids tableRowIDs) GetID(i int) string {
+	return ids[i]
+}
+
+func (ids tableRowIDs) Count() int {
+	return len(ids)
+}
+
+func (v *TableView) getRowIDs() tableRowIDs {
+	ids := make(tableRowIDs, len(v.rows))
+	for i, r := range v.rows {
+		ids[i] = r.id
+	}
+	return ids
+}
+
+// updateRows makes the rows shown from the slice, if rows are used.
+// For a tree that is each row, followed by its children if it is expanded.
+// A row with its details expanded is followed by a detail row. Rows hidden by filters are left out,
+// unless they have children that pass, which are then shown below them even if not expanded.
+func (v *TableView) updateRows() {
+	_, hasIDGetter := tableGetSliceRValFromPointer(v.structure).Interface().(zui.ListViewIDGetter)
+	// rows are also used for ids made from tags, so they are made once for all rows, with suffixes for duplicates
+	v.useRows = (v.treeFieldName != "" || v.RowDetails || v.isFiltering() || !hasIDGetter)
+	if !v.useRows {
+		v.rows = nil
+		v.rowIndexes = nil
+		return
+	}
+	v.rows = v.rows[:0]
+	v.rowIndexes = map[string]int{}
+	v.addRows(tableGetSliceRValFromPointer(v.structure), 0, "")
+}
+
+func (v *TableView) addRows(slice reflect.Value, depth int, parentID string) {
+	getter := getTreeRowIDs(slice, parentID)
+	for i := 0; i < slice.Len(); i++ {
+		rval := slice.Index(i)
+		var row tableRow
+		row.data = rval.Addr().Interface()
+		var children reflect.Value
+		if v.treeFieldName != "" {
+			children = rval.FieldByName(v.treeFieldName)
+			row.hasChildren = (children.Len() != 0)
+		}
+		isAncestor := false
+		if v.isFiltering() && !v.rowPassesFilters(row.data) {
+			if !row.hasChildren || !v.hasRowsPassingFilters(children) {
+				continue
+			}
+			isAncestor = true
+		}
+		row.id = getter.GetID(i)
+		row.depth = depth
+		v.rowIndexes[row.id] = len(v.rows)
+		v.rows = append(v.rows, row)
+		if v.detailsExpanded[row.id] {
+			detail := tableRow{data: row.data, id: row.id + tableDetailRowIDSuffix, depth: depth, isDetail: true}
+			v.rowIndexes[detail.id] = len(v.rows)
+			v.rows = append(v.rows, detail)
+		}
+		if row.hasChildren && (v.expanded[row.id] || isAncestor) {
+			v.addRows(children, depth+1, row.id)
+		}
+	}
+}
+
+// hasRowsPassingFilters returns true if any row in slice, or its children in a tree, passes the filters.
+func (v *TableView) hasRowsPassingFilters(slice reflect.Value) bool {
+	for i := 0; i < slice.Len(); i++ {
+		rval := slice.Index(i)
+		if v.rowPassesFilters(rval.Addr().Interface()) {
+			return true
+		}
+		if v.treeFieldName != "" && v.hasRowsPassingFilters(rval.FieldByName(v.treeFieldName)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sortSlice sorts slice with the header's sort order. If the table is a tree, each level of children is sorted too.
+func (v *TableView) sortSlice(slice interface{}) {
+	SortSliceWithFields(slice, v.fields, v.Header.SortOrder)
+	if v.treeFieldName == "" {
+		return
+	}
+	val := reflect.ValueOf(slice)
+	for i := 0; i < val.Len(); i++ {
+		unlock := lockRow(val, i, false)
+		children := val.Index(i).FieldByName(v.treeFieldName)
+		unlock()
+		if children.Len() != 0 {
+			v.sortSlice(children.Interface())
+		}
+	}
+}
+
+// hasDisclosureColumn returns true if rows have a first column with tree or detail toggles.
+func (v *TableView) hasDisclosureColumn() bool {
+	return v.treeFieldName != "" || v.RowDetails
+}
+
+func (v *TableView) addTreeHeader(headers []zui.Header) []zui.Header {
+	if !v.hasDisclosureColumn() {
+		return headers
+	}
+	var h zui.Header
+	h.ID = tableTreeColumnID
+	h.Height = v.HeaderHeight - 6
+	h.Align = zgeo.Left | zgeo.VertCenter
+	return append([]zui.Header{h}, headers...)
+}
+
+func getTreeDisclosureText(row *tableRow, expanded bool) string {
+	if !row.hasChildren {
+		return ""
+	}
+	if expanded {
+		return "▾"
+	}
+	return "▸"
+}
+
+// addTreeDisclosure adds a cell first in a tree's row, indented by depth, with a disclosure toggle if it has children.
+// If RowDetails is set, it also has a toggle to show the row's details.
+func (v *TableView) addTreeDisclosure(fv *FieldView, rowID string) {
+	if !v.hasDisclosureColumn() {
+		return
+	}
+	stack := zui.StackViewHor(tableTreeColumnID)
+	label := zui.LabelNew("")
+	label.SetObjectName("disclosure")
+	stack.Add(label, zgeo.CenterLeft)
+	if v.RowDetails {
+		detailLabel := zui.LabelNew("")
+		detailLabel.SetObjectName("details")
+		stack.Add(detailLabel, zgeo.CenterLeft)
+	}
+	cell := zui.ContainerViewCell{}
+	cell.View = stack
+	cell.Alignment = zgeo.CenterLeft
+	cell.MinSize.W = v.TreeWidth
+	cell.MaxSize.W = v.TreeWidth
+	fv.AddCell(cell, -1)
+	v.bindTreeDisclosure(fv, rowID)
+}
+
+// bindTreeDisclosure sets the indent and toggles of the disclosure cell in fv for the row with rowID.
+// It is called again when a row's view is recycled for another row.
+func (v *TableView) bindTreeDisclosure(fv *FieldView, rowID string) {
+	i := v.getRowIndexFromID(rowID)
+	if !v.hasDisclosureColumn() || i == -1 || !v.useRows {
+		return
+	}
+	row := &v.rows[i]
+	view, _ := fv.FindViewWithName(tableTreeColumnID, true)
+	stack, _ := view.(*zui.StackView)
+	if stack != nil {
+		stack.SetMargin(zgeo.RectFromXY2(v.TreeIndent*float64(row.depth), 0, 0, 0))
+	}
+	view, _ = fv.FindViewWithName("disclosure", true)
+	label, _ := view.(*zui.Label)
+	if label != nil {
+		label.SetText(getTreeDisclosureText(row, v.expanded[rowID]))
+		label.SetPressedHandler(func() {
+			v.SetRowExpanded(rowID, !v.IsRowExpanded(rowID))
+		})
+	}
+	view, _ = fv.FindViewWithName("details", true)
+	detailLabel, _ := view.(*zui.Label)
+	if detailLabel != nil {
+		detailLabel.SetText(getDetailToggleText(v.detailsExpanded[rowID]))
+		detailLabel.SetPressedHandler(func() {
+			v.SetRowDetailExpanded(rowID, !v.IsRowDetailExpanded(rowID))
+		})
+	}
+}
+
+func (v *TableView) updateTreeDisclosure(fv *FieldView, i int) {
+	if !v.hasDisclosureColumn() || i >= len(v.rows) || v.rows[i].isDetail {
+		return
+	}
+	row := &v.rows[i]
+	view, _ := fv.FindViewWithName("disclosure", true)
+	label, _ := view.(*zui.Label)
+	if label != nil {
+		label.SetText(getTreeDisclosureText(row, v.expanded[row.id]))
+	}
+	view, _ = fv.FindViewWithName("details", true)
+	label, _ = view.(*zui.Label)
+	if label != nil {
+		label.SetText(getDetailToggleText(v.detailsExpanded[row.id]))
+	}
+}
+
+func (v *TableView) IsRowExpanded(rowID string) bool {
+	return v.expanded[rowID]
+}
+
+// SetRowExpanded shows or hides the children of the row with rowID in a tree table.
+// Expanded rows stay expanded when the table is updated with UpdateWithOldNewSlice.
+func (v *TableView) SetRowExpanded(rowID string, expanded bool) {
+	if v.expanded[rowID] == expanded {
+		return
+	}
+	if expanded {
+		v.expanded[rowID] = true
+	} else {
+		delete(v.expanded, rowID)
+	}
+	if v.treeFieldName == "" {
+		return
+	}
+	v.updateRowsAround(rowID)
+}
+
+// updateRowsAround updates the rows shown after rows under rowID have been shown or hidden, updating its toggles.
+func (v *TableView) updateRowsAround(rowID string) {
+	oldIDs := v.getRowIDs()
+	v.updateRows()
+	v.List.UpdateWithOldNewSlice(oldIDs, v.getRowIDs())
+	i := v.getRowIndexFromID(rowID)
+	if i != -1 {
+		fv, _ := v.List.GetVisibleRowViewFromIndex(i).(*FieldView)
+		if fv != nil {
+			v.updateTreeDisclosure(fv, i)
+		}
+	}
+}
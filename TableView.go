@@ -18,6 +18,8 @@ import (
 var (
 	TableDefaultRowHoverColor = zui.StyleColF(zgeo.ColorNew(0.8, 0.91, 1, 1), zgeo.ColorNew(0.3, 0.34, 0.4, 1))
 	TableDefaultUseHeader     = zdevice.IsDesktop()
+	TableDefaultChangedColor  = zgeo.ColorNew(1, 0.85, 0.3, 1)
+	TableDefaultInsertedColor = zgeo.ColorNew(0.5, 0.9, 0.5, 1)
 	TableDefaultGetRowColor   = func(i int) zgeo.Color {
 		if i%2 == 0 {
 			return zui.StyleGray(0.97, 0.05)
@@ -30,10 +32,29 @@ type TableView struct {
 	zui.StackView
 	List          *zui.ListView
 	Header        *zui.HeaderView
+	Footer        *zui.HeaderView // Footer is created if any field has a total tag, showing the total in the column
 	ColumnMargin  float64
 	RowInset      float64
 	DefaultHeight float64
 	HeaderHeight  float64
+	FlashChanges  bool       // FlashChanges makes cells flash when their shown value changes, and new rows highlight
+	FlashSecs     float64    // FlashSecs is how long a flash or highlight lasts
+	ChangedColor  zgeo.Color // ChangedColor is used to flash changed cells
+	InsertedColor zgeo.Color // InsertedColor is used to highlight new rows
+	// CellNavigation makes arrow keys and tab move a cell cursor. Return or typing edits the cell, return again stores it, and escape cancels
+	CellNavigation bool
+	TreeIndent     float64 // TreeIndent is how much each level of a tree is indented in its disclosure column
+	TreeWidth      float64 // TreeWidth is the width of the disclosure column of a tree
+	// RowDetails adds a toggle to each row, showing all its fields, including static and tablehide ones, in a row below it
+	RowDetails bool
+	// MeasureRowHeights makes each row as high as its content, measured from its laid-out view
+	MeasureRowHeights bool
+	// ColumnFilterMenus makes a long press on a column's header show a popup to filter rows on its values
+	ColumnFilterMenus bool
+	CopyRows          bool    // CopyRows makes ctrl/cmd-C copy the selected row as TSV
+	PasteRows         bool    // PasteRows makes ctrl/cmd-V paste TSV rows, updating rows with the same id or adding them
+	Ordered           bool    // Ordered lets alt-up/down arrows and MoveRow re-order the slice's rows, if not sorted by the header
+	EditHoldOffSecs   float64 // EditHoldOffSecs is how long an edited cell isn't overwritten by updates, if not zero
 
 	SortedIndexes []int
 	GetRowCount   func() int
@@ -43,9 +64,35 @@ type TableView struct {
 	//	RowDataUpdated func(i int)
 	HeaderPressed     func(id string)
 	HeaderLongPressed func(id string)
+	RowsUpdated       func()             // RowsUpdated is called after UpdateWithOldNewSlice has updated the rows
+	RowMoved          func(rowID string) // RowMoved is called after a row is moved with MoveRow
 
-	structure interface{}
-	fields    []Field
+	structure     interface{}
+	fields        []Field             // fields are the columns shown
+	allFields     []Field             // allFields are all the fields that can be shown as columns, in struct order
+	fieldsOrdered bool                // fieldsOrdered is set when fields are in the order of an applied state, not the struct's
+	columnWidths  map[string]float64  // columnWidths are widths set for columns, by field id
+	stateName     string              // stateName is the name of the stored state last applied or saved
+	rowTexts      map[string][]string // rowTexts are the texts shown in each row, by row id, used to find changes when FlashChanges is set
+	cursor        tableCursor
+	stopFade      func()     // stopFade stops fading out removed rows, removing them
+	reloadQueued  bool       // reloadQueued is set while a reload of the list is queued after row heights changed
+	emptyRowView  *FieldView // emptyRowView is a row of an empty struct, to fit the header to when there are no rows
+
+	treeFieldName string          // treeFieldName is the name of the slice field with a children tag, making the table a tree
+	expanded      map[string]bool // expanded is the rows of a tree showing their children, by row id
+	useRows       bool            // useRows is set if rows is used for the table's rows instead of the slice directly
+	rows          []tableRow
+	rowIndexes    map[string]int
+
+	detailsExpanded map[string]bool    // detailsExpanded is the rows showing a detail row, by row id
+	rowHeights      map[string]float64 // rowHeights is the measured height of rows, by row id
+
+	rowPool      []*FieldView        // rowPool is row views removed from the list, to re-use for new rows
+	recycledRows map[*FieldView]bool // recycledRows is the views in rowPool
+
+	filter        func(rowData interface{}) bool
+	columnFilters map[string]*ColumnFilter // columnFilters are the filters on columns, by field id
 }
 
 func tableGetSliceRValFromPointer(structure interface{}) reflect.Value {
@@ -64,24 +111,67 @@ func tableGetSliceRValFromPointer(structure interface{}) reflect.Value {
 	return reflect.ValueOf(n)
 }
 
+// makeTableFields makes the fields for columns of a table of structure's type.
+func makeTableFields(structure interface{}) []Field {
+	var fields []Field
+	options := zreflect.Options{UnnestAnonymous: true, MakeSliceElementIfNone: true}
+	froot, err := zreflect.ItterateStruct(structure, options)
+	if err != nil {
+		panic(err)
+	}
+	for i, item := range froot.Children {
+		var f Field
+		immediateEdit := false
+		if f.makeFromReflectItem(structure, item, i, immediateEdit) {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// TableViewNew creates a table with a row for each struct in the slice structData points to.
+// If the slice isn't a zui.ListViewIDGetter, row ids are the value of a field with an id tag, or a hash of fields with key tags.
 func TableViewNew(name string, header bool, structData interface{}) *TableView {
 	// zlog.Info("TableViewNew:", name, header)
 	v := &TableView{}
 	v.StackView.Init(v, true, name)
+	v.AddOnRemoveFunc(func() {
+		unregisterChangeListener(v)
+	})
 	v.SetSpacing(0)
 	v.ColumnMargin = 5
 	v.RowInset = 7
 	v.HeaderHeight = 28
 	v.DefaultHeight = 30
+	v.FlashSecs = 0.6
+	v.ChangedColor = TableDefaultChangedColor
+	v.InsertedColor = TableDefaultInsertedColor
+	v.TreeIndent = 14
+	v.TreeWidth = 80
+	v.expanded = map[string]bool{}
+	v.detailsExpanded = map[string]bool{}
+	v.rowHeights = map[string]float64{}
+	v.columnFilters = map[string]*ColumnFilter{}
+	v.columnWidths = map[string]float64{}
+	v.recycledRows = map[*FieldView]bool{}
 	v.structure = structData
 
 	var structure interface{}
 	rval := tableGetSliceRValFromPointer(structData)
 	if !rval.IsNil() {
 		v.GetRowCount = func() int {
+			if v.useRows {
+				return len(v.rows)
+			}
 			return tableGetSliceRValFromPointer(structData).Len()
 		}
 		v.GetRowData = func(i int) interface{} {
+			if v.useRows {
+				if i < len(v.rows) {
+					return v.rows[i].data
+				}
+				return nil
+			}
 			val := tableGetSliceRValFromPointer(structData)
 			if val.Len() != 0 {
 				return val.Index(i).Addr().Interface()
@@ -108,29 +198,27 @@ func TableViewNew(name string, header bool, structData interface{}) *TableView {
 		// 	return nil
 		// }
 	}
-	options := zreflect.Options{UnnestAnonymous: true, MakeSliceElementIfNone: true}
-	froot, err := zreflect.ItterateStruct(structure, options)
-	if err != nil {
-		panic(err)
-	}
-
-	for i, item := range froot.Children {
-		var f Field
-		immediateEdit := false
-		if f.makeFromReflectItem(structure, item, i, immediateEdit) {
-			v.fields = append(v.fields, f)
+	for _, f := range makeTableFields(structure) {
+		if f.Flags&flagIsTreeChildren != 0 {
+			v.treeFieldName = f.FieldName
+			continue
+		}
+		if f.Flags&flagTableHide != 0 {
+			continue
 		}
+		v.fields = append(v.fields, f)
 	}
+	v.allFields = append([]Field{}, v.fields...)
 	if header {
 		v.Header = zui.HeaderViewNew(name + ".header")
 		v.Add(v.Header, zgeo.Left|zgeo.Top|zgeo.HorExpand)
 		v.Header.SortingPressed = func() {
 			val := tableGetSliceRValFromPointer(structData)
 			nval := reflect.MakeSlice(val.Type(), val.Len(), val.Len())
-			reflect.Copy(nval, val)
+			copyRowsLocked(nval, val)
 			nslice := nval.Interface()
 			slice := val.Interface()
-			SortSliceWithFields(nslice, v.fields, v.Header.SortOrder)
+			v.sortSlice(nslice)
 			val.Set(nval)
 			v.UpdateWithOldNewSlice(slice, nslice)
 		}
@@ -145,6 +233,13 @@ func TableViewNew(name string, header bool, structData interface{}) *TableView {
 	v.List.HighlightColor = TableDefaultRowHoverColor()
 	v.List.HoverHighlight = true
 	v.Add(v.List, zgeo.Left|zgeo.Top|zgeo.Expand)
+	for _, f := range v.fields {
+		if f.Total != "" {
+			v.Footer = zui.HeaderViewNew(name + ".footer")
+			v.Add(v.Footer, zgeo.Left|zgeo.Bottom|zgeo.HorExpand)
+			break
+		}
+	}
 	if !rval.IsNil() {
 		v.List.RowUpdater = func(i int, edited bool) {
 			v.FlushDataToRow(i, edited)
@@ -159,12 +254,17 @@ func TableViewNew(name string, header bool, structData interface{}) *TableView {
 	}
 	v.List.CreateRow = func(rowSize zgeo.Size, i int) zui.View {
 		// start := time.Now()
-		getter := tableGetSliceRValFromPointer(structData).Interface().(zui.ListViewIDGetter)
-		rowID := getter.GetID(i)
+		rowID := v.getRowID(i)
 		r := v.createRow(rowSize, rowID, i)
 		return r
 	}
 	v.GetRowHeight = func(i int) float64 {
+		if v.isDetailRow(i) {
+			return v.getDetailRowHeight(i)
+		}
+		if v.MeasureRowHeights {
+			return v.getMeasuredRowHeight(i)
+		}
 		return v.DefaultHeight
 	}
 	v.List.GetRowHeight = func(i int) float64 {
@@ -173,63 +273,109 @@ func TableViewNew(name string, header bool, structData interface{}) *TableView {
 	v.List.GetRowCount = func() int {
 		return v.GetRowCount()
 	}
+	if !rval.IsNil() {
+		v.updateRows()
+	}
 	return v
 }
 
 func (v *TableView) ArrangeChildren() {
 	v.StackView.ArrangeChildren()
-	if v.Header == nil {
+	if v.Header == nil && v.Footer == nil {
 		return
 	}
 	freeOnly := true
-	v.Header.ArrangeAdvanced(freeOnly)
-	if v.Header != nil {
-		if v.GetRowCount() > 0 {
-			first, _ := v.List.GetFirstLastVisibleRowIndexes()
-			view := v.List.GetVisibleRowViewFromIndex(first)
-			zlog.Assert(view != nil)
-			fv := view.(*FieldView)
-			v.Header.FitToRowStack(&fv.StackView, v.ColumnMargin)
-		} else { // no rows, make an empty one to fit header with
+	var rowStack *zui.StackView
+	if v.GetRowCount() > 0 {
+		first, last := v.List.GetFirstLastVisibleRowIndexes()
+		for i := first; i <= last; i++ {
+			if !v.isDetailRow(i) { // detail rows are vertical, and can't be fitted to
+				view := v.List.GetVisibleRowViewFromIndex(i)
+				zlog.Assert(view != nil)
+				fv := view.(*FieldView)
+				rowStack = &fv.StackView
+				break
+			}
+		}
+	}
+	if rowStack == nil { // no rows, use an empty one to fit header with, made again if columns have changed
+		if v.emptyRowView == nil || !v.hasRowFields(v.emptyRowView) {
 			val := reflect.ValueOf(v.structure)
 			sliceType := val.Elem().Type()
 			newSlice := reflect.MakeSlice(sliceType, 1, 1)
 			emptyRowStruct := newSlice.Index(0).Addr().Interface()
-			emptyRowView := v.createRowFromData(emptyRowStruct, "").(*FieldView)
-			emptyRowView.SetRect(v.LocalRect())
-			emptyRowView.ArrangeChildren()
-			v.Header.FitToRowStack(&emptyRowView.StackView, v.ColumnMargin)
+			v.emptyRowView = v.createRowFromData(emptyRowStruct, "").(*FieldView)
+			v.emptyRowView.unregisterTimeLabels() // it is never shown, so never removed
 		}
+		v.emptyRowView.SetRect(v.LocalRect())
+		v.emptyRowView.ArrangeChildren()
+		rowStack = &v.emptyRowView.StackView
+	}
+	if v.Header != nil {
+		v.Header.ArrangeAdvanced(freeOnly)
+		v.Header.FitToRowStack(rowStack, v.ColumnMargin)
+	}
+	if v.Footer != nil {
+		v.Footer.ArrangeAdvanced(freeOnly)
+		v.Footer.FitToRowStack(rowStack, v.ColumnMargin)
 	}
 }
 
 func (v *TableView) ReadyToShow(beforeWindow bool) {
 	// zlog.Info("TV: ReadyToShow", beforeWindow, )
+	if beforeWindow && v.ColumnFilterMenus {
+		v.loadColumnFilters()
+	}
 	if beforeWindow && v.Header != nil {
-		headers := makeHeaderFields(v.fields, v.HeaderHeight)
+		headers := v.addTreeHeader(makeHeaderFields(v.fields, v.HeaderHeight))
 		v.Header.Populate(headers)
 		v.Header.HeaderPressed = v.HeaderPressed
 		v.Header.HeaderLongPressed = v.HeaderLongPressed
+		if v.ColumnFilterMenus {
+			v.Header.HeaderLongPressed = func(id string) {
+				if v.findField(id) != nil {
+					v.PresentColumnFilter(id)
+				}
+				if v.HeaderLongPressed != nil {
+					v.HeaderLongPressed(id)
+				}
+			}
+			v.updateHeaderFilterMarks()
+		}
 		slice := tableGetSliceRValFromPointer(v.structure).Interface()
 		var sid string
-		getter := slice.(zui.ListViewIDGetter)
 		if v.List.SelectionIndex() != -1 {
-			sid = getter.GetID(v.List.SelectionIndex())
+			sid = v.getRowID(v.List.SelectionIndex())
 		}
-		SortSliceWithFields(slice, v.fields, v.Header.SortOrder)
+		v.sortSlice(slice)
+		v.updateRows()
 		if sid != "" {
-			count := v.GetRowCount()
-			for i := 0; i < count; i++ {
-				if getter.GetID(i) == sid {
-					v.List.Select(i, false, false)
-					break
-				}
+			i := v.getRowIndexFromID(sid)
+			if i != -1 {
+				v.List.Select(i, false, false)
 			}
 		}
 	}
+	if beforeWindow && v.Header == nil {
+		v.updateRows() // RowDetails or filters might have been set after TableViewNew
+	}
+	if beforeWindow {
+		v.listenForChanges()
+	}
+	if beforeWindow && (v.CellNavigation || v.CopyRows || v.PasteRows || v.Ordered) {
+		v.setupKeyHandler()
+	}
+	if beforeWindow && v.FlashChanges {
+		v.flashChangedRows() // this stores the texts of all rows, nothing flashes as there are none before
+	}
+	if beforeWindow && v.Footer != nil {
+		v.Footer.Populate(v.addTreeHeader(makeFooterFields(v.fields, v.HeaderHeight)))
+		v.updateFooter()
+	}
 }
 
 func (v *TableView) Reload() {
+	v.updateRows()
 	v.List.ReloadData()
 }
 
@@ -246,7 +392,38 @@ func (v *TableView) SetStructureList(list interface{}) {
 	}
 }
 
-func (v *TableView) FlashRow() {
+func (v *TableView) getRowID(i int) string {
+	if v.useRows {
+		return v.rows[i].id
+	}
+	return getSliceRowID(tableGetSliceRValFromPointer(v.structure), i)
+}
+
+// IsRowEditedRecently returns true if the row with rowID is shown and was edited less than EditHoldOffSecs ago.
+func (v *TableView) IsRowEditedRecently(rowID string) bool {
+	i := v.getRowIndexFromID(rowID)
+	if i == -1 {
+		return false
+	}
+	fv, _ := v.List.GetVisibleRowViewFromIndex(i).(*FieldView)
+	return fv != nil && fv.IsEditedRecently()
+}
+
+func (v *TableView) getRowIndexFromID(id string) int {
+	if v.useRows {
+		i, got := v.rowIndexes[id]
+		if !got {
+			return -1
+		}
+		return i
+	}
+	count := v.GetRowCount()
+	for i := 0; i < count; i++ {
+		if v.getRowID(i) == id {
+			return i
+		}
+	}
+	return -1
 }
 
 func (v *TableView) FlushDataToRow(i int, edited bool) {
@@ -255,13 +432,24 @@ func (v *TableView) FlushDataToRow(i int, edited bool) {
 	if fv != nil {
 		data := v.GetRowData(i)
 		if data != nil {
+			if v.isDetailRow(i) {
+				fv.SetStructure(data)
+				fv.Update(!edited)
+				v.updateRowHeight(i)
+				return
+			}
 			fv.SetStructure(data)
 			dontOverwriteEdited := !edited
 			// zlog.Info("TV: FlushDataToRow:", dontOverwriteEdited, i, data)
 			fv.Update(dontOverwriteEdited)
+			v.updateTreeDisclosure(fv, i)
+			v.flashChangedRow(i, fv)
+			v.updateRowHeight(i)
 		}
 		// getter := tableGetSliceRValFromPointer(v.structure).Interface().(zui.ListViewIDGetter)
+		return
 	}
+	v.updateRowHeight(i) // rows not shown have their heights estimated again
 }
 
 func (v *TableView) createRow(rowSize zgeo.Size, rowID string, i int) zui.View {
@@ -269,6 +457,14 @@ func (v *TableView) createRow(rowSize zgeo.Size, rowID string, i int) zui.View {
 	// zlog.Info("createRow:", time.Since(start))
 	data := v.GetRowData(i)
 	// zlog.Info("createRow2:", time.Since(start))
+	setNotifierHandler(data)
+	if v.isDetailRow(i) {
+		return v.createDetailRow(data, rowID)
+	}
+	fv := v.getRecycledRow(data, rowID, i)
+	if fv != nil {
+		return fv
+	}
 	return v.createRowFromData(data, rowID)
 }
 
@@ -277,17 +473,26 @@ func (v *TableView) createRowFromData(data interface{}, rowID string) zui.View {
 	params := FieldViewParametersDefault()
 	params.ImmediateEdit = false
 	params.Spacing = 0
+	if v.EditHoldOffSecs != 0 {
+		params.EditHoldOffSecs = v.EditHoldOffSecs
+	}
 	fv := FieldViewNew(rowID, data, params)
 	fv.Vertical = false
 	fv.fields = v.fields
+	fv.fieldsOrdered = v.fieldsOrdered
 	fv.SetSpacing(0)
 	fv.SetCanFocus(true)
 	fv.SetMargin(zgeo.RectMake(v.RowInset, 0, -math.Max(16, v.RowInset), 0))
+	v.addTreeDisclosure(fv, rowID)
 	//	rowStruct := v.GetRowData(i)
 	useWidth := true //(v.Header != nil)
 	// zlog.Info("createRow4:", time.Since(start))
 	showStatic := true
 	fv.buildStack(name, zgeo.CenterLeft, showStatic, zgeo.Size{v.ColumnMargin, 0}, useWidth, v.RowInset)
+	v.showCursorInNewRow(fv, rowID)
+	if rowID != "" {
+		v.addRowToRecycle(fv)
+	}
 	// zlog.Info("createRow5:", time.Since(start))
 	// edited := false
 	// v.handleUpdate(edited, i)
@@ -297,6 +502,23 @@ func (v *TableView) createRowFromData(data interface{}, rowID string) zui.View {
 	return fv
 }
 
+// rowsUpdated is called after UpdateWithOldNewSlice has updated the list's rows.
+func (v *TableView) rowsUpdated() {
+	v.flashChangedRows()
+	v.updateFooter()
+	v.updateCursor()
+	if v.RowsUpdated != nil {
+		v.RowsUpdated()
+	}
+}
+
+func getHeaderTitle(f *Field) string {
+	if f.Title != "" {
+		return f.Title
+	}
+	return f.Name
+}
+
 func makeHeaderFields(fields []Field, height float64) []zui.Header {
 	var headers []zui.Header
 	for _, f := range fields {
@@ -320,10 +542,7 @@ func makeHeaderFields(fields []Field, height float64) []zui.Header {
 			// zlog.Info("makeHeaderFields:", f.Name, h.ImageSize, h.ImagePath, f)
 		}
 		if f.Flags&(flagHasHeaderImage|flagNoTitle) == 0 {
-			h.Title = f.Title
-			if h.Title == "" {
-				h.Title = f.Name
-			}
+			h.Title = getHeaderTitle(&f)
 		}
 		if f.Tooltip != "" && !strings.HasPrefix(f.Tooltip, ".") {
 			h.Tip = f.Tooltip
@@ -336,17 +555,44 @@ func makeHeaderFields(fields []Field, height float64) []zui.Header {
 }
 
 func (v *TableView) UpdateWithOldNewSlice(oldSlice, newSlice interface{}) {
-	oldGetter := oldSlice.(zui.ListViewIDGetter)
-	newGetter := newSlice.(zui.ListViewIDGetter)
 	// zlog.Info("SLICE5:", oldGetter.GetID(5))
 	// var focusedRowID, focusedElementObjectName string
 	// start := time.Now()
 	// zlog.Info("UpdateWithOldNewSlice:", v.ObjectName())
 	if v.Header != nil {
 		// zlog.Info("SortSliceWithFields:", v.ObjectName(), v.Header.SortOrder)
-		SortSliceWithFields(newSlice, v.fields, v.Header.SortOrder)
+		v.sortSlice(newSlice)
+	}
+	v.cancelFade()
+	var oldGetter, newGetter zui.ListViewIDGetter
+	var oldData func(i int) interface{}
+	if v.useRows {
+		oldGetter = v.getRowIDs()
+		oldRows := append([]tableRow{}, v.rows...)
+		oldData = func(i int) interface{} {
+			if oldRows[i].isDetail {
+				return nil
+			}
+			return oldRows[i].data
+		}
+		v.updateRows()
+		newGetter = v.getRowIDs()
+	} else {
+		oldGetter = getSliceIDGetter(oldSlice)
+		newGetter = getSliceIDGetter(newSlice)
+		oldVal := reflect.ValueOf(oldSlice)
+		oldData = func(i int) interface{} {
+			return oldVal.Index(i).Addr().Interface()
+		}
+	}
+	var removed []tableRemovedRow
+	if v.FlashChanges {
+		removed = v.getRemovedRows(oldGetter, newGetter, oldData)
 	}
+	v.pruneRowHeights(oldGetter, newGetter)
 	v.List.UpdateWithOldNewSlice(oldGetter, newGetter)
+	v.rowsUpdated()
+	v.fadeOutRemovedRows(removed)
 	// zlog.Info("UpdateWithOldNewSlice:", v.ObjectName(), time.Since(start))
 	// if focusedRowID != "" {
 	// 	v.List.Scroll
@@ -0,0 +1,119 @@
+//go:build zui
+// +build zui
+
+package zfields
+
+import (
+	"time"
+
+	"github.com/torlangballe/zui"
+	"github.com/torlangballe/zutil/zreflect"
+	"github.com/torlangballe/zutil/ztimer"
+)
+
+// timeLabel is a label showing time since or until, or colored by age, updated by the shared time ticker.
+type timeLabel struct {
+	fieldView *FieldView
+	field     *Field
+	interval  time.Duration
+	next      time.Time
+}
+
+// timeLabels are all labels updated by the time ticker. They are only used on the UI loop, so not locked.
+var (
+	timeLabels        = map[*zui.Label]*timeLabel{}
+	timeTickerRunning bool
+)
+
+// getTimeLabelInterval returns how often a label for f changes: each second if it shows seconds, or every 10 seconds for minutes, or minute for hours.
+func getTimeLabelInterval(f *Field) time.Duration {
+	if f.Flags&flagIsDuration == 0 || f.Flags&flagHasSeconds != 0 {
+		return time.Second // oldsecs and colorif labels change color at any second
+	}
+	if f.Flags&flagHasHours != 0 && f.Flags&flagHasMinutes == 0 {
+		return time.Minute
+	}
+	return time.Second * 10
+}
+
+// registerTimeLabel makes label for f be updated by the shared ticker, until it or v is removed.
+// Remove functions are only added once for each label, as v might be re-used as a table row.
+func (v *FieldView) registerTimeLabel(label *zui.Label, f *Field) {
+	timeLabels[label] = &timeLabel{fieldView: v, field: f, interval: getTimeLabelInterval(f)}
+	if v.timeLabels == nil {
+		v.timeLabels = map[*zui.Label]bool{}
+		v.AddOnRemoveFunc(func() {
+			for l := range v.timeLabels {
+				delete(timeLabels, l)
+			}
+		})
+	}
+	if !v.timeLabels[label] {
+		v.timeLabels[label] = true
+		zui.ViewGetNative(label).AddOnRemoveFunc(func() {
+			delete(timeLabels, label)
+		})
+	}
+	if !timeTickerRunning {
+		timeTickerRunning = true
+		ztimer.RepeatNow(1, tickTimeLabels)
+	}
+}
+
+// unregisterTimeLabels stops the labels of v being updated, for views that are never shown, so never removed.
+func (v *FieldView) unregisterTimeLabels() {
+	for l := range v.timeLabels {
+		delete(timeLabels, l)
+	}
+}
+
+// registerTimeLabels registers the time labels of v again, after it is re-used as a table row.
+func (v *FieldView) registerTimeLabels() {
+	for i, f := range v.fields {
+		if !isTimeLabelField(&f) {
+			continue
+		}
+		view, _ := v.findNamedViewOrInLabelized(f.ID)
+		label, ok := view.(*zui.Label)
+		if ok {
+			v.registerTimeLabel(label, &v.fields[i])
+		}
+	}
+}
+
+func isTimeLabelField(f *Field) bool {
+	return f.Kind == zreflect.KindTime && f.IsStatic() && (f.Flags&flagIsDuration != 0 || len(f.ColorRules) != 0)
+}
+
+// tickTimeLabels updates the labels that are due, stopping the ticker when there are none.
+func tickTimeLabels() bool {
+	if len(timeLabels) == 0 {
+		timeTickerRunning = false
+		return false
+	}
+	now := time.Now()
+	for label, tl := range timeLabels {
+		if now.Before(tl.next) {
+			continue
+		}
+		tl.next = now.Add(tl.interval)
+		if tl.field.Flags&flagIsDuration != 0 {
+			tl.fieldView.updateSinceTime(label, tl.field)
+		} else {
+			tl.fieldView.updateOldTime(label, tl.field)
+		}
+	}
+	return true
+}
+
+// signalTimeUp sends TimeUpAction for until field f, once for each time t it counts down to.
+func (v *FieldView) signalTimeUp(f *Field, t time.Time, label *zui.Label) {
+	if v.timesUp == nil {
+		v.timesUp = map[string]time.Time{}
+	}
+	if v.timesUp[f.ID].Equal(t) {
+		return
+	}
+	v.timesUp[f.ID] = t
+	v.callActionHandlerFunc(f, TimeUpAction, t, &label.View)
+}